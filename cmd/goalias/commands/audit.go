@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jackchuka/goalias/internal/discovery"
+	"github.com/jackchuka/goalias/pkg/goalias"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [packages]",
+	Short: "Inventory every aliased import",
+	Long: `Inventory every import in the specified Go packages.
+
+For each import path that is aliased somewhere, audit reports every name it is
+used under with counts and example locations. Unaliased imports count towards
+the package's own name. Paths used under more than one name are marked with *.
+Blank (_) imports are ignored.
+
+Examples:
+  goalias audit
+  goalias audit ./internal/...
+  goalias audit --inconsistent
+  goalias audit --format json`,
+	RunE: runAudit,
+}
+
+var (
+	auditFormat       string
+	auditAll          bool
+	auditInconsistent bool
+	auditExamples     int
+
+	auditDiscovery discovery.Options
+)
+
+// auditRecord is the machine-readable form of an audit entry
+type auditRecord struct {
+	goalias.AuditEntry
+	Inconsistent bool `json:"inconsistent"`
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringVar(&auditFormat, "format", "table", "Output format: table or json")
+	auditCmd.Flags().BoolVar(&auditAll, "all", false, "Include import paths that are never aliased")
+	auditCmd.Flags().BoolVar(&auditInconsistent, "inconsistent", false, "Only report import paths used under more than one name")
+	auditCmd.Flags().IntVar(&auditExamples, "examples", 3, "Number of example locations per alias")
+	addDiscoveryFlags(auditCmd, &auditDiscovery, true)
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	switch auditFormat {
+	case "table", "json":
+	default:
+		return fmt.Errorf("unknown format %q: must be table or json", auditFormat)
+	}
+
+	if auditExamples < 0 {
+		return fmt.Errorf("--examples must not be negative")
+	}
+
+	all, err := goalias.Audit(cmd.Context(), scanOptions(args, auditDiscovery), auditExamples)
+	if err != nil {
+		return err
+	}
+
+	var entries []goalias.AuditEntry
+	for _, e := range all {
+		if !auditAll && !e.Aliased() {
+			continue
+		}
+		if auditInconsistent && !e.Inconsistent() {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	if auditFormat == "json" {
+		records := make([]auditRecord, 0, len(entries))
+		for _, e := range entries {
+			records = append(records, auditRecord{AuditEntry: e, Inconsistent: e.Inconsistent()})
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No aliased imports found")
+		return nil
+	}
+
+	return writeAuditTable(os.Stdout, entries)
+}
+
+func writeAuditTable(out io.Writer, entries []goalias.AuditEntry) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "IMPORT PATH\tALIAS\tCOUNT\tEXAMPLES")
+	_, _ = fmt.Fprintln(w, "-----------\t-----\t-----\t--------")
+
+	inconsistent := 0
+	for _, e := range entries {
+		path := "  " + e.Path
+		if e.Inconsistent() {
+			path = "* " + e.Path
+			inconsistent++
+		}
+
+		for i, u := range e.Usages {
+			if i > 0 {
+				path = ""
+			}
+
+			alias := u.Alias
+			if u.Alias == e.PackageName {
+				alias += " (package name)"
+			}
+
+			examples := make([]string, 0, len(u.Examples))
+			for _, l := range u.Examples {
+				examples = append(examples, fmt.Sprintf("%s:%d", relativePath(l.File), l.Line))
+			}
+
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", path, alias, u.Count, strings.Join(examples, ", "))
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if inconsistent > 0 {
+		_, _ = fmt.Fprintf(out, "\n%d import path(s) are used under more than one name (marked *)\n", inconsistent)
+	}
+
+	return nil
+}
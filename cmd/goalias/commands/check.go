@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackchuka/goalias/internal/discovery"
+	"github.com/jackchuka/goalias/pkg/goalias"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check [packages]",
+	Short: "Report imports that violate the alias policy",
+	Long: `Report every import whose alias differs from the one required by the alias
+policy file (.goalias.yaml) and exit non-zero if any are found. check never
+modifies files and does not start gopls, which makes it suitable for CI.
+	
+Examples:
+  goalias check
+  goalias check ./cmd/...
+  goalias check --format=github
+  goalias check -p github.com/example/mypackage=mypkg`,
+	RunE:          runCheck,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var (
+	checkPackages []string
+	checkAlias    string
+	checkFormat   string
+
+	checkDiscovery discovery.Options
+)
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().StringArrayVarP(&checkPackages, "package", "p", nil, "Full import path to check, optionally as path=alias; repeatable (default: all packages in the policy file)")
+	checkCmd.Flags().StringVarP(&checkAlias, "alias", "a", "", "Required alias identifier (default: alias from the policy file)")
+	checkCmd.Flags().StringVar(&checkFormat, "format", "text", "Output format: text or github")
+	addDiscoveryFlags(checkCmd, &checkDiscovery, true)
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	write := goalias.WriteText
+	switch checkFormat {
+	case "text":
+	case "github":
+		write = goalias.WriteGitHub
+	default:
+		return fmt.Errorf("unknown format %q: must be text or github", checkFormat)
+	}
+
+	p, err := loadPolicy()
+	if err != nil {
+		return err
+	}
+
+	targets, err := resolveTargets(checkPackages, checkAlias, p)
+	if err != nil {
+		return err
+	}
+
+	violations, err := goalias.Check(cmd.Context(), scanOptions(args, checkDiscovery), p, targets...)
+	if err != nil {
+		return err
+	}
+
+	if err := write(os.Stdout, violations, relativePath); err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("found %d import alias violation(s)", len(violations))
+	}
+
+	return nil
+}
+
+// relativePath returns file relative to the current directory when it is
+// below it, and file unchanged otherwise
+func relativePath(file string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return file
+	}
+
+	rel, err := filepath.Rel(cwd, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return file
+	}
+
+	return filepath.ToSlash(rel)
+}
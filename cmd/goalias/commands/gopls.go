@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackchuka/goalias/pkg/goalias"
+	"github.com/spf13/cobra"
+	"go.lsp.dev/protocol"
+)
+
+// goplsFlags configures the gopls process of a rewriting command. The flags
+// are layered over the gopls section of the policy file.
+type goplsFlags struct {
+	Path     string
+	Args     []string
+	Env      []string
+	Settings string
+
+	LoadTimeout    time.Duration
+	RequestTimeout time.Duration
+
+	Verbose bool
+	Trace   string
+
+	Restarts int
+}
+
+// addGoplsFlags registers the flags configuring gopls
+func addGoplsFlags(cmd *cobra.Command, flags *goplsFlags) {
+	cmd.Flags().StringVar(&flags.Path, "gopls-path", "", "gopls binary to run (default: gopls setting of the policy file, then gopls from $PATH)")
+	cmd.Flags().StringArrayVar(&flags.Args, "gopls-arg", nil, "Extra argument to gopls serve, such as -remote=auto; repeatable")
+	cmd.Flags().StringArrayVar(&flags.Env, "gopls-env", nil, "Environment variable for gopls as KEY=VALUE, such as GOFLAGS=-mod=mod; repeatable")
+	cmd.Flags().StringVar(&flags.Settings, "gopls-settings", "", `gopls settings as a JSON object, such as '{"buildFlags":["-tags=integration"]}'`)
+	cmd.Flags().DurationVar(&flags.LoadTimeout, "gopls-load-timeout", goalias.DefaultGoplsLoadTimeout, "How long to wait for gopls to load the workspace")
+	cmd.Flags().DurationVar(&flags.RequestTimeout, "gopls-timeout", goalias.DefaultGoplsRequestTimeout, "How long to wait for each gopls rename")
+	cmd.Flags().IntVar(&flags.Restarts, "gopls-restarts", 0, "Restart gopls up to this many times if it crashes, resuming with the import being renamed")
+	cmd.Flags().BoolVarP(&flags.Verbose, "verbose", "v", false, "Show the log messages and standard error of gopls")
+	cmd.Flags().StringVar(&flags.Trace, "lsp-trace", "", "Record every JSON-RPC message exchanged with gopls, with timestamps, to this file")
+}
+
+// options returns the gopls settings of the policy file overridden by the
+// flags
+func (f goplsFlags) options(p *goalias.Policy) (goalias.GoplsOptions, error) {
+	if f.LoadTimeout <= 0 || f.RequestTimeout <= 0 {
+		return goalias.GoplsOptions{}, fmt.Errorf("--gopls-load-timeout and --gopls-timeout must be positive")
+	}
+
+	if f.Restarts < 0 {
+		return goalias.GoplsOptions{}, fmt.Errorf("--gopls-restarts must not be negative")
+	}
+
+	override := goalias.GoplsOptions{
+		Path:           f.Path,
+		Args:           f.Args,
+		LoadTimeout:    f.LoadTimeout,
+		RequestTimeout: f.RequestTimeout,
+		Progress:       printGoplsProgress,
+		MaxRestarts:    f.Restarts,
+		Restarting: func(cause error) {
+			_, _ = fmt.Fprintf(os.Stderr, "warning: %v; restarting gopls\n", cause)
+		},
+	}
+	if f.Verbose {
+		override.Stderr = os.Stderr
+		override.Log = printGoplsLog
+	}
+
+	for _, kv := range f.Env {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return goalias.GoplsOptions{}, fmt.Errorf("invalid --gopls-env %q: must be KEY=VALUE", kv)
+		}
+		if override.Env == nil {
+			override.Env = make(map[string]string)
+		}
+		override.Env[key] = value
+	}
+
+	if f.Settings != "" {
+		if err := json.Unmarshal([]byte(f.Settings), &override.Settings); err != nil {
+			return goalias.GoplsOptions{}, fmt.Errorf("invalid --gopls-settings: must be a JSON object: %w", err)
+		}
+	}
+
+	return p.Gopls().Merge(override), nil
+}
+
+// printGoplsProgress shows what gopls is busy with, such as loading the
+// workspace before the first rename
+func printGoplsProgress(p goalias.GoplsProgress) {
+	switch p.Kind {
+	case "begin", "report":
+		line := "gopls: " + p.Title
+		if p.Message != "" {
+			line += ": " + p.Message
+		}
+		if p.Percentage != nil {
+			line += fmt.Sprintf(" (%d%%)", *p.Percentage)
+		}
+		_, _ = fmt.Fprintln(os.Stderr, line)
+	case "end":
+		if p.Message != "" {
+			_, _ = fmt.Fprintf(os.Stderr, "gopls: %s\n", p.Message)
+		}
+	}
+}
+
+// printGoplsLog shows a message gopls logged, for --verbose
+func printGoplsLog(typ protocol.MessageType, message string) {
+	level := "log"
+	switch typ {
+	case protocol.MessageTypeError:
+		level = "error"
+	case protocol.MessageTypeWarning:
+		level = "warning"
+	case protocol.MessageTypeInfo:
+		level = "info"
+	case protocol.MessageTypeDebug:
+		level = "debug"
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "gopls %s: %s\n", level, strings.TrimRight(message, "\n"))
+}
@@ -1,11 +1,17 @@
 package commands
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"text/tabwriter"
+	"text/template"
 
 	"github.com/jackchuka/goalias/internal/discovery"
+	"github.com/jackchuka/goalias/pkg/goalias"
 	"github.com/spf13/cobra"
 )
 
@@ -13,43 +19,205 @@ var listCmd = &cobra.Command{
 	Use:   "list [packages]",
 	Short: "List import aliases for a package",
 	Long: `List all files and their aliases for a package within specified Go packages.
+
+When --package is omitted, every package in the alias policy file
+(.goalias.yaml) is listed.
+
+--format selects the output: table (default), json, jsonl, csv, or template.
+Machine-readable formats emit one record per import with the fields file,
+line, column, importPath, declaredAlias, effectiveAlias, explicit and package.
+With --format=template, --template is a Go text/template executed for each
+record, using the field names File, Line, Column, ImportPath, DeclaredAlias,
+EffectiveAlias, Explicit and Package.
 	
 Examples:
   goalias list -p github.com/example/mypackage
-  goalias list -p github.com/example/mypackage ./cmd/...`,
+  goalias list -p github.com/example/mypackage ./cmd/...
+  goalias list -p github.com/example/mypackage --format json
+  goalias list -p github.com/example/mypackage --format template --template '{{.File}}:{{.Line}} {{.EffectiveAlias}}'
+  goalias list`,
 	RunE: runList,
 }
 
-var listPackage string
+var (
+	listPackage  string
+	listFormat   string
+	listTemplate string
+
+	listDiscovery discovery.Options
+)
+
+// listRecord is the machine-readable form of an import
+type listRecord struct {
+	File           string `json:"file"`
+	Line           int    `json:"line"`
+	Column         int    `json:"column"`
+	ImportPath     string `json:"importPath"`
+	DeclaredAlias  string `json:"declaredAlias"`
+	EffectiveAlias string `json:"effectiveAlias"`
+	Explicit       bool   `json:"explicit"`
+	Package        string `json:"package"`
+}
 
 func init() {
 	rootCmd.AddCommand(listCmd)
 
-	listCmd.Flags().StringVarP(&listPackage, "package", "p", "", "Full import path to manage (required)")
-
-	_ = listCmd.MarkFlagRequired("package")
+	listCmd.Flags().StringVarP(&listPackage, "package", "p", "", "Full import path to manage (default: all packages in the policy file)")
+	listCmd.Flags().StringVar(&listFormat, "format", "table", "Output format: table, json, jsonl, csv or template")
+	listCmd.Flags().StringVar(&listTemplate, "template", "", "Go template executed per import when --format=template")
+	addDiscoveryFlags(listCmd, &listDiscovery, false)
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	patterns := discovery.GetPatterns(args)
+	var tmpl *template.Template
+	switch listFormat {
+	case "table", "json", "jsonl", "csv":
+		if listTemplate != "" {
+			return fmt.Errorf("--template requires --format=template")
+		}
+	case "template":
+		if listTemplate == "" {
+			return fmt.Errorf("--format=template requires --template")
+		}
+		var err error
+		if tmpl, err = template.New("list").Parse(listTemplate); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown format %q: must be table, json, jsonl, csv or template", listFormat)
+	}
 
-	results, err := discovery.FindImportsInFiles(patterns, listPackage)
+	p, err := loadPolicy()
 	if err != nil {
 		return err
 	}
 
+	opts := scanOptions(args, listDiscovery)
+
+	// A single package is listed whether or not the policy covers it
+	var results []goalias.Requirement
+	if listPackage != "" {
+		target, err := goalias.ParseTarget(listPackage)
+		if err != nil {
+			return err
+		}
+
+		imports, err := goalias.Scan(cmd.Context(), opts, target.Path)
+		if err != nil {
+			return err
+		}
+
+		for _, imp := range imports {
+			required := target.Alias
+			if required == "" {
+				required, _ = p.AliasFor(imp.File, imp.Path)
+			}
+			results = append(results, goalias.Requirement{Import: imp, Required: required})
+		}
+	} else {
+		targets, err := resolveTargets(nil, "", p)
+		if err != nil {
+			return err
+		}
+
+		if results, err = goalias.Evaluate(cmd.Context(), opts, p, targets...); err != nil {
+			return err
+		}
+	}
+
+	records := make([]listRecord, 0, len(results))
+	for _, r := range results {
+		records = append(records, listRecord{
+			File:           r.File,
+			Line:           r.Line,
+			Column:         r.Column,
+			ImportPath:     r.Path,
+			DeclaredAlias:  r.Name,
+			EffectiveAlias: r.Alias,
+			Explicit:       r.Explicit(),
+			Package:        r.Package,
+		})
+	}
+
+	switch listFormat {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case "jsonl":
+		encoder := json.NewEncoder(os.Stdout)
+		for _, r := range records {
+			if err := encoder.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "csv":
+		return writeListCSV(os.Stdout, records)
+	case "template":
+		for _, r := range records {
+			if err := tmpl.Execute(os.Stdout, r); err != nil {
+				return fmt.Errorf("failed to execute template: %w", err)
+			}
+			fmt.Println()
+		}
+		return nil
+	}
+
 	if len(results) == 0 {
-		fmt.Printf("No imports found for package: %s\n", listPackage)
+		if listPackage != "" {
+			fmt.Printf("No imports found for package: %s\n", listPackage)
+		} else {
+			fmt.Println("No imports found for configured packages")
+		}
 		return nil
 	}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "LOCATION\tALIAS")
-	_, _ = fmt.Fprintln(w, "--------\t-----")
+	return writeListTable(os.Stdout, results, listPackage == "")
+}
+
+func writeListTable(out io.Writer, results []goalias.Requirement, multiple bool) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	// A single package keeps the original two-column layout
+	if !multiple {
+		_, _ = fmt.Fprintln(w, "LOCATION\tALIAS")
+		_, _ = fmt.Fprintln(w, "--------\t-----")
+
+		for _, r := range results {
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", r.Location(), r.Alias)
+		}
+
+		return w.Flush()
+	}
+
+	_, _ = fmt.Fprintln(w, "PACKAGE\tLOCATION\tALIAS\tREQUIRED")
+	_, _ = fmt.Fprintln(w, "-------\t--------\t-----\t--------")
 
 	for _, r := range results {
-		_, _ = fmt.Fprintf(w, "%s\t%s\n", r.Location, r.Alias)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Path, r.Location(), r.Alias, r.Required)
 	}
 
 	return w.Flush()
 }
+
+func writeListCSV(out io.Writer, records []listRecord) error {
+	w := csv.NewWriter(out)
+
+	_ = w.Write([]string{"file", "line", "column", "importPath", "declaredAlias", "effectiveAlias", "explicit", "package"})
+	for _, r := range records {
+		_ = w.Write([]string{
+			r.File,
+			strconv.Itoa(r.Line),
+			strconv.Itoa(r.Column),
+			r.ImportPath,
+			r.DeclaredAlias,
+			r.EffectiveAlias,
+			strconv.FormatBool(r.Explicit),
+			r.Package,
+		})
+	}
+
+	w.Flush()
+	return w.Error()
+}
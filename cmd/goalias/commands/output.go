@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jackchuka/goalias/internal/diff"
+	"github.com/jackchuka/goalias/pkg/goalias"
+	"github.com/spf13/cobra"
+)
+
+// Diff colouring modes selectable with --color
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// outputOptions selects what a rewriting command does with its changes
+type outputOptions struct {
+	Preview bool
+	Patch   string
+	Context int
+	Color   string
+}
+
+// addOutputFlags registers the flags selecting how changes are written
+func addOutputFlags(cmd *cobra.Command, opts *outputOptions) {
+	cmd.Flags().BoolVarP(&opts.Preview, "preview", "n", false, "Show diff instead of writing changes")
+	cmd.Flags().StringVar(&opts.Patch, "patch", "", "Write changes as a patch for git apply to this file instead of modifying files")
+	cmd.Flags().IntVar(&opts.Context, "context", 3, "Number of context lines in --preview and --patch diffs")
+	cmd.Flags().StringVar(&opts.Color, "color", colorAuto, "Colour --preview output: auto, always or never")
+}
+
+func (o outputOptions) validate() error {
+	switch o.Color {
+	case colorAuto, colorAlways, colorNever:
+	default:
+		return fmt.Errorf("unknown color mode %q: must be %s, %s or %s", o.Color, colorAuto, colorAlways, colorNever)
+	}
+
+	if o.Context < 0 {
+		return fmt.Errorf("--context must not be negative")
+	}
+
+	if o.Preview && o.Patch != "" {
+		return fmt.Errorf("--preview cannot be combined with --patch")
+	}
+
+	return nil
+}
+
+// applyChanges writes a patch, prints a preview, or writes all files
+// atomically as selected by opts
+func applyChanges(ctx context.Context, changes []goalias.FileChange, opts outputOptions) error {
+	switch {
+	case opts.Patch != "":
+		return writePatch(opts.Patch, changes, opts.Context)
+	case opts.Preview:
+		printPreview(changes, opts)
+		return nil
+	}
+
+	return goalias.Apply(ctx, changes)
+}
+
+// printPreview prints a unified diff of every change to stdout
+func printPreview(changes []goalias.FileChange, opts outputOptions) {
+	color := opts.Color == colorAlways || (opts.Color == colorAuto && isTerminal(os.Stdout))
+
+	for _, c := range changes {
+		path := relativePath(c.Path)
+		out := diff.Unified(path, path, string(c.Original), string(c.Modified), opts.Context)
+		if color {
+			out = diff.Colorize(out)
+		}
+		fmt.Print(out)
+	}
+}
+
+// writePatch writes every change to path as a patch git apply accepts when
+// run from the current directory
+func writePatch(path string, changes []goalias.FileChange, contextLines int) error {
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(diff.GitPatch(relativePath(c.Path), string(c.Original), string(c.Modified), contextLines))
+	}
+
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write patch: %w", err)
+	}
+
+	fmt.Printf("Wrote patch for %d file(s) to %s\n", len(changes), path)
+	return nil
+}
+
+// isTerminal reports whether f is a terminal and colour has not been
+// disabled with NO_COLOR
+func isTerminal(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
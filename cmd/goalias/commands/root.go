@@ -1,6 +1,14 @@
 package commands
 
 import (
+	"context"
+	"fmt"
+	"go/token"
+	"os"
+	"os/signal"
+
+	"github.com/jackchuka/goalias/internal/discovery"
+	"github.com/jackchuka/goalias/pkg/goalias"
 	"github.com/spf13/cobra"
 )
 
@@ -11,6 +19,106 @@ var rootCmd = &cobra.Command{
 goalias automatically manages import aliases in Go projects, ensuring consistency across all files using the Go Language Server (gopls) for fast, accurate refactoring.`,
 }
 
+var configPath string
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Path to alias policy file (default: "+goalias.PolicyFileName+" discovered from the current directory)")
+}
+
+// Execute runs the root command. An interrupt cancels the command's context,
+// which stops a migration before anything is written.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return rootCmd.ExecuteContext(ctx)
+}
+
+// addDiscoveryFlags registers the flags selecting which files are scanned
+func addDiscoveryFlags(cmd *cobra.Command, opts *discovery.Options, testsDefault bool) {
+	cmd.Flags().BoolVar(&opts.Tests, "tests", testsDefault, "Include _test.go files and external test packages")
+	cmd.Flags().BoolVar(&opts.AllBuildTags, "all-build-tags", false, "Include files excluded by build constraints")
+	cmd.Flags().StringSliceVar(&opts.Tags, "tags", nil, "Build tags to apply when listing packages (comma-separated)")
+	cmd.Flags().StringSliceVar(&opts.GOOS, "goos", nil, "GOOS values to scan; files from every value are combined (comma-separated)")
+	cmd.Flags().StringSliceVar(&opts.GOARCH, "goarch", nil, "GOARCH values to scan; files from every value are combined (comma-separated)")
+}
+
+// scanOptions combines the package patterns given as arguments with the
+// discovery flags of a command
+func scanOptions(args []string, opts discovery.Options) goalias.Options {
+	return goalias.Options{
+		Patterns:     args,
+		Tests:        opts.Tests,
+		AllBuildTags: opts.AllBuildTags,
+		Tags:         opts.Tags,
+		GOOS:         opts.GOOS,
+		GOARCH:       opts.GOARCH,
+	}
+}
+
+// loadPolicy loads the policy named by --config, or discovers one by walking
+// up from the current directory. It returns nil when no policy exists.
+func loadPolicy() (*goalias.Policy, error) {
+	if configPath != "" {
+		return goalias.LoadPolicy(configPath)
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current working directory: %w", err)
+	}
+
+	return goalias.DiscoverPolicy(cwd)
+}
+
+// resolveTargets returns the import paths a command operates on, given by
+// --package as "path" or "path=alias". alias applies to packages given without
+// one. When no packages are given it returns no targets, meaning every import
+// the policy file has a rule for.
+func resolveTargets(packages []string, alias string, p *goalias.Policy) ([]goalias.Target, error) {
+	if len(packages) == 0 {
+		if alias != "" {
+			return nil, fmt.Errorf("--alias requires --package")
+		}
+
+		if p == nil {
+			return nil, fmt.Errorf("--package is required when no %s is found", goalias.PolicyFileName)
+		}
+
+		if p.Empty() {
+			return nil, fmt.Errorf("%s has no rules", p.Path())
+		}
+
+		return nil, nil
+	}
+
+	if alias != "" && !token.IsIdentifier(alias) {
+		return nil, fmt.Errorf("invalid alias %q: not a Go identifier", alias)
+	}
+
+	targets := make([]goalias.Target, 0, len(packages))
+	seen := make(map[string]string)
+
+	for _, pkg := range packages {
+		target, err := goalias.ParseTarget(pkg)
+		if err != nil {
+			return nil, err
+		}
+
+		if target.Alias == "" {
+			target.Alias = alias
+		}
+
+		if prev, ok := seen[target.Path]; ok {
+			if prev != target.Alias {
+				return nil, fmt.Errorf("conflicting aliases %q and %q for %s", prev, target.Alias, target.Path)
+			}
+			continue
+		}
+		seen[target.Path] = target.Alias
+
+		targets = append(targets, target)
+	}
+
+	return targets, nil
 }
@@ -1,11 +1,13 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 
 	"github.com/jackchuka/goalias/internal/discovery"
-	"github.com/jackchuka/goalias/internal/lsp"
+	"github.com/jackchuka/goalias/pkg/goalias"
 	"github.com/spf13/cobra"
 )
 
@@ -13,102 +15,261 @@ var setCmd = &cobra.Command{
 	Use:   "set [packages]",
 	Short: "Set import alias for a package",
 	Long: `Set import alias for a package across specified Go packages.
+
+--package may be repeated and given as path=alias to update many packages in a
+single run, or the pairs can be read from a mapping file with one path=alias
+per line. When --package is omitted, every rule in the alias policy file
+(.goalias.yaml) is applied. When no alias is given, it comes from the policy
+file.
 	
 Examples:
   goalias set -p github.com/example/mypackage -a mypkg
-  goalias set -p github.com/example/mypackage -a mypkg ./cmd/...`,
+  goalias set -p github.com/example/mypackage -a mypkg ./cmd/...
+  goalias set -p github.com/example/a=pkga -p github.com/example/b=pkgb
+  goalias set --mapping aliases.txt
+  goalias set --engine ast
+  goalias set --gopls-path ./bin/gopls --gopls-env GOFLAGS=-mod=mod
+  goalias set --preview --context 5
+  goalias set --patch aliases.diff
+  goalias set`,
 	RunE: runSet,
 }
 
 var (
-	setPackage string
-	setAlias   string
-	setPreview bool
+	setPackages []string
+	setAlias    string
+	setMapping  string
+	setEngine   string
+	setGopls    goplsFlags
+	setOutput   outputOptions
+
+	setOnConflict    string
+	setFallbackAlias string
+	setDotImports    string
+
+	setDiscovery discovery.Options
+)
+
+// Conflict policies selectable with --on-conflict
+const (
+	conflictAbort    = "abort"
+	conflictSkip     = "skip"
+	conflictFallback = "fallback"
+)
+
+// Dot import handling selectable with --dot-imports
+const (
+	dotImportsSkip    = "skip"
+	dotImportsConvert = "convert"
+)
+
+// Rename engines selectable with --engine
+const (
+	engineGopls = "gopls"
+	engineAST   = "ast"
 )
 
 func init() {
 	rootCmd.AddCommand(setCmd)
 
-	setCmd.Flags().StringVarP(&setPackage, "package", "p", "", "Full import path to manage (required)")
-	setCmd.Flags().StringVarP(&setAlias, "alias", "a", "", "Desired alias identifier (required)")
-	setCmd.Flags().BoolVarP(&setPreview, "preview", "n", false, "Show diff instead of writing changes")
-
-	_ = setCmd.MarkFlagRequired("package")
-	_ = setCmd.MarkFlagRequired("alias")
+	setCmd.Flags().StringArrayVarP(&setPackages, "package", "p", nil, "Full import path to manage, optionally as path=alias; repeatable (default: all packages in the policy file)")
+	setCmd.Flags().StringVarP(&setAlias, "alias", "a", "", "Desired alias identifier (default: alias from the policy file)")
+	setCmd.Flags().StringVar(&setMapping, "mapping", "", "File of path=alias pairs to apply, one per line")
+	setCmd.Flags().StringVar(&setEngine, "engine", engineGopls, "Rename engine: gopls (language server) or ast (built-in, no gopls required)")
+	setCmd.Flags().StringVar(&setOnConflict, "on-conflict", conflictAbort, "When the alias collides with an identifier in a file: abort, skip the file, or fallback to --fallback-alias")
+	setCmd.Flags().StringVar(&setFallbackAlias, "fallback-alias", "{alias}{n}", "Alias pattern tried in conflicting files with --on-conflict=fallback; {n} counts from 2")
+	setCmd.Flags().StringVar(&setDotImports, "dot-imports", dotImportsSkip, "Dot imports: skip them, or convert them to named imports, qualifying every reference")
+	addGoplsFlags(setCmd, &setGopls)
+	addOutputFlags(setCmd, &setOutput)
+	addDiscoveryFlags(setCmd, &setDiscovery, true)
 }
 
 func runSet(cmd *cobra.Command, args []string) error {
-	patterns := discovery.GetPatterns(args)
+	switch setOnConflict {
+	case conflictAbort, conflictSkip, conflictFallback:
+	default:
+		return fmt.Errorf("unknown conflict policy %q: must be %s, %s or %s", setOnConflict, conflictAbort, conflictSkip, conflictFallback)
+	}
+
+	switch setDotImports {
+	case dotImportsSkip, dotImportsConvert:
+	default:
+		return fmt.Errorf("unknown dot import mode %q: must be %s or %s", setDotImports, dotImportsSkip, dotImportsConvert)
+	}
+
+	if err := setOutput.validate(); err != nil {
+		return err
+	}
 
-	results, err := discovery.FindImportsInFiles(patterns, setPackage)
+	p, err := loadPolicy()
 	if err != nil {
 		return err
 	}
 
-	var filesToProcess []discovery.ImportResult
+	gopls, err := setGopls.options(p)
+	if err != nil {
+		return err
+	}
 
-	for _, result := range results {
-		// Use the effective alias (which includes inferred default aliases)
-		if result.Alias == setAlias {
-			continue
+	var targets []goalias.Target
+	if setMapping != "" {
+		targets, err = readMapping(setMapping, setPackages, setAlias)
+	} else {
+		targets, err = resolveTargets(setPackages, setAlias, p)
+	}
+	if err != nil {
+		return err
+	}
+
+	violations, err := goalias.Check(cmd.Context(), scanOptions(args, setDiscovery), p, targets...)
+	if err != nil {
+		return err
+	}
+
+	var fixable, unfixable []goalias.Violation
+	for _, v := range violations {
+		if v.Fixable() {
+			fixable = append(fixable, v)
+		} else {
+			unfixable = append(unfixable, v)
 		}
-		filesToProcess = append(filesToProcess, result)
 	}
 
-	if len(filesToProcess) == 0 {
+	if len(unfixable) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: %d violation(s) cannot be fixed automatically:\n", len(unfixable))
+		_ = goalias.WriteText(os.Stderr, unfixable, relativePath)
+	}
+
+	if len(fixable) == 0 {
 		fmt.Println("No files need updating")
 		return nil
 	}
 
-	// Get current working directory for LSP client
-	cwd, err := os.Getwd()
+	// Everything below is a runtime failure rather than a usage error
+	cmd.SilenceUsage = true
+
+	engine, closeEngine, err := newEngine(cmd.Context(), setEngine, gopls, setGopls.Trace)
 	if err != nil {
-		return fmt.Errorf("failed to get current working directory: %w", err)
+		return err
 	}
+	defer closeEngine()
 
-	// Create LSP client
-	client, err := lsp.NewClient(cwd)
+	// Every rename is computed against the unmodified tree and nothing is
+	// written until all of them succeed
+	changes, err := goalias.Plan(cmd.Context(), fixable, goalias.PlanOptions{
+		Engine:            engine,
+		OnConflict:        goalias.ConflictPolicy(setOnConflict),
+		FallbackAlias:     setFallbackAlias,
+		ConvertDotImports: setDotImports == dotImportsConvert,
+		Progress: func(i, n int, v goalias.Violation) {
+			// Progress goes to stderr so --preview prints a clean patch
+			if i == 1 {
+				fmt.Fprintf(os.Stderr, "Processing %d imports...\n", n)
+			}
+			fmt.Fprintf(os.Stderr, "Processing import %d/%d: %s (%s)\n", i, n, v.File, v.Path)
+		},
+		Notify: printNotice,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create LSP client: %w", err)
+		var conflictErr *goalias.ConflictError
+		if errors.As(err, &conflictErr) {
+			return fmt.Errorf("%w; use --on-conflict=skip or --on-conflict=fallback to continue", err)
+		}
+		return fmt.Errorf("%w; no files were modified", err)
 	}
-	defer func() {
-		_ = client.Close()
-	}()
 
-	// Initialize LSP client
-	if err := client.Initialize(); err != nil {
-		return fmt.Errorf("failed to initialize LSP client: %w", err)
+	if len(changes) == 0 {
+		fmt.Println("No files need updating")
+		return nil
 	}
 
-	fmt.Printf("Processing %d files...\n", len(filesToProcess))
+	return applyChanges(cmd.Context(), changes, setOutput)
+}
+
+// printNotice reports what a migration skipped or handled specially
+func printNotice(n goalias.Notice) {
+	v := n.Violation
+	file := relativePath(v.File)
 
-	// Process files using LSP client
-	for i, result := range filesToProcess {
-		fmt.Printf("Processing file %d/%d: %s\n", i+1, len(filesToProcess), result.File)
+	switch n.Kind {
+	case goalias.NoticeDotImportSkipped:
+		fmt.Fprintf(os.Stderr, "warning: skipping dot import of %s in %s (use --dot-imports=convert to convert it)\n", v.Path, file)
+	case goalias.NoticeConflict:
+		fmt.Fprintf(os.Stderr, "%s:%d:%d: %s\n", relativePath(n.Position.Filename), n.Position.Line, n.Position.Column, n.Message)
+	case goalias.NoticeConflictSkipped:
+		fmt.Fprintf(os.Stderr, "warning: skipping %s\n", file)
+	case goalias.NoticeFallback:
+		fmt.Fprintf(os.Stderr, "using alias %q for %s in %s\n", n.Alias, v.Path, file)
+	case goalias.NoticeNoFallback:
+		fmt.Fprintf(os.Stderr, "no fallback alias matching %q is free in %s\n", setFallbackAlias, file)
+	case goalias.NoticeMerged:
+		fmt.Fprintf(os.Stderr, "merging duplicate imports of %s in %s\n", v.Path, file)
+	}
+}
+
+// newEngine creates the named rename engine and a function releasing it.
+// gopls configures the process of the gopls engine, and trace names the file
+// its messages are recorded to, if any.
+func newEngine(ctx context.Context, name string, gopls goalias.GoplsOptions, trace string) (goalias.Engine, func(), error) {
+	switch name {
+	case engineAST:
+		return goalias.ASTEngine(), func() {}, nil
+	case engineGopls:
+	default:
+		return nil, nil, fmt.Errorf("unknown engine %q: must be %s or %s", name, engineGopls, engineAST)
+	}
 
-		if err := processFileWithLSP(client, result); err != nil {
-			return fmt.Errorf("failed to process %s: %w", result.File, err)
+	// Get current working directory for LSP client
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get current working directory: %w", err)
+	}
+
+	closeTrace := func() {}
+	if trace != "" {
+		f, err := os.Create(trace)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create LSP trace: %w", err)
 		}
+		gopls.Trace = f
+		closeTrace = func() { _ = f.Close() }
+	}
+
+	engine, err := goalias.StartGopls(ctx, cwd, gopls)
+	if err != nil {
+		closeTrace()
+		return nil, nil, err
 	}
 
-	return nil
+	return engine, func() {
+		_ = engine.Close()
+		closeTrace()
+	}, nil
 }
 
-func processFileWithLSP(client *lsp.Client, result discovery.ImportResult) error {
-	// Convert Go token position to LSP position (0-based)
-	line := result.Info.Position.Line - 1
-	column := result.Info.Position.Column - 1
+// readMapping loads targets from a mapping file. It cannot be combined with
+// --package or --alias.
+func readMapping(path string, packages []string, alias string) ([]goalias.Target, error) {
+	if len(packages) > 0 || alias != "" {
+		return nil, fmt.Errorf("--mapping cannot be combined with --package or --alias")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mapping file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
 
-	// Perform rename operation
-	workspaceEdit, err := client.Rename(result.File, line, column, setAlias)
+	targets, err := goalias.ParseMapping(f)
 	if err != nil {
-		return fmt.Errorf("rename operation failed: %w", err)
+		return nil, fmt.Errorf("%s: %w", path, err)
 	}
 
-	// Apply the workspace edit
-	if err := lsp.ApplyWorkspaceEdit(workspaceEdit, setPreview); err != nil {
-		return fmt.Errorf("failed to apply workspace edit: %w", err)
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s: no mappings found", path)
 	}
 
-	return nil
+	return targets, nil
 }
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jackchuka/goalias/internal/discovery"
+	"github.com/jackchuka/goalias/pkg/goalias"
+	"github.com/spf13/cobra"
+)
+
+var suggestCmd = &cobra.Command{
+	Use:   "suggest [packages]",
+	Short: "Suggest canonical aliases from current usage",
+	Long: `Suggest a canonical alias for each import path used under more than one name.
+
+The name most imports already use wins. Ties go to the name following Go
+conventions (lowercase, no underscores, not shadowing a standard library
+package), then to the package's own name, then alphabetically.
+
+With --output the suggestions are written as a starter policy file, ready to
+be reviewed and applied with goalias set.
+
+Examples:
+  goalias suggest
+  goalias suggest --all --output .goalias.yaml
+  goalias suggest --format json`,
+	RunE: runSuggest,
+}
+
+var (
+	suggestFormat string
+	suggestAll    bool
+	suggestOutput string
+	suggestForce  bool
+
+	suggestDiscovery discovery.Options
+)
+
+func init() {
+	rootCmd.AddCommand(suggestCmd)
+
+	suggestCmd.Flags().StringVar(&suggestFormat, "format", "table", "Output format: table or json")
+	suggestCmd.Flags().BoolVar(&suggestAll, "all", false, "Also suggest aliases for import paths consistently aliased to a name other than the package name")
+	suggestCmd.Flags().StringVarP(&suggestOutput, "output", "o", "", "Write the suggestions as a policy file to this path (- for stdout)")
+	suggestCmd.Flags().BoolVar(&suggestForce, "force", false, "Overwrite an existing --output file")
+	addDiscoveryFlags(suggestCmd, &suggestDiscovery, true)
+}
+
+func runSuggest(cmd *cobra.Command, args []string) error {
+	switch suggestFormat {
+	case "table", "json":
+	default:
+		return fmt.Errorf("unknown format %q: must be table or json", suggestFormat)
+	}
+
+	if suggestOutput != "" && suggestOutput != "-" && !suggestForce {
+		if _, err := os.Stat(suggestOutput); err == nil {
+			return fmt.Errorf("%s already exists; use --force to overwrite it", suggestOutput)
+		}
+	}
+
+	all, err := goalias.Audit(cmd.Context(), scanOptions(args, suggestDiscovery), 0)
+	if err != nil {
+		return err
+	}
+
+	var entries []goalias.AuditEntry
+	for _, e := range all {
+		if e.Inconsistent() || (suggestAll && e.Aliased() && e.Usages[0].Alias != e.PackageName) {
+			entries = append(entries, e)
+		}
+	}
+
+	suggestions, err := goalias.Suggest(cmd.Context(), entries)
+	if err != nil {
+		return err
+	}
+
+	if suggestOutput != "" {
+		return writeSuggestedConfig(suggestOutput, suggestions)
+	}
+
+	if suggestFormat == "json" {
+		if suggestions == nil {
+			suggestions = []goalias.Suggestion{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(suggestions)
+	}
+
+	if len(suggestions) == 0 {
+		fmt.Println("No inconsistent imports found")
+		return nil
+	}
+
+	return writeSuggestTable(os.Stdout, suggestions)
+}
+
+func writeSuggestTable(out io.Writer, suggestions []goalias.Suggestion) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(w, "IMPORT PATH\tSUGGESTED\tVOTES\tALTERNATIVES")
+	_, _ = fmt.Fprintln(w, "-----------\t---------\t-----\t------------")
+
+	for _, s := range suggestions {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\n", s.Path, s.Alias, s.Votes, s.Total, strings.Join(s.Alternatives, ", "))
+	}
+
+	return w.Flush()
+}
+
+// writeSuggestedConfig writes suggestions as a policy file to path, or to
+// stdout when path is "-"
+func writeSuggestedConfig(path string, suggestions []goalias.Suggestion) error {
+	var buf bytes.Buffer
+	if err := goalias.WriteSuggestedPolicy(&buf, suggestions); err != nil {
+		return err
+	}
+
+	if path == "-" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write policy file: %w", err)
+	}
+
+	fmt.Printf("Wrote %d rule(s) to %s\n", len(suggestions), path)
+	return nil
+}
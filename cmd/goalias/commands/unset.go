@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jackchuka/goalias/internal/discovery"
+	"github.com/jackchuka/goalias/pkg/goalias"
+	"github.com/spf13/cobra"
+)
+
+var unsetCmd = &cobra.Command{
+	Use:   "unset [packages]",
+	Short: "Remove import aliases for a package",
+	Long: `Remove import aliases for a package across specified Go packages.
+
+By default only redundant aliases are removed: those equal to the imported
+package's own name, such as fmt "fmt". With --force every alias is removed and
+its usages are renamed back to the package name; files where the package name
+would collide with another identifier are skipped.
+
+Blank (_) and dot (.) imports are never changed.
+
+Examples:
+  goalias unset -p github.com/example/mypackage
+  goalias unset -p github.com/example/mypackage ./cmd/...
+  goalias unset -p fmt --force`,
+	RunE: runUnset,
+}
+
+var (
+	unsetPackages []string
+	unsetForce    bool
+	unsetEngine   string
+	unsetGopls    goplsFlags
+	unsetOutput   outputOptions
+
+	unsetDiscovery discovery.Options
+)
+
+func init() {
+	rootCmd.AddCommand(unsetCmd)
+
+	unsetCmd.Flags().StringArrayVarP(&unsetPackages, "package", "p", nil, "Full import path to remove aliases for; repeatable (required)")
+	unsetCmd.Flags().BoolVar(&unsetForce, "force", false, "Remove every alias, renaming usages back to the package name")
+	unsetCmd.Flags().StringVar(&unsetEngine, "engine", engineGopls, "Rename engine used by --force: gopls (language server) or ast (built-in, no gopls required)")
+	addGoplsFlags(unsetCmd, &unsetGopls)
+	addOutputFlags(unsetCmd, &unsetOutput)
+	addDiscoveryFlags(unsetCmd, &unsetDiscovery, true)
+
+	_ = unsetCmd.MarkFlagRequired("package")
+}
+
+func runUnset(cmd *cobra.Command, args []string) error {
+	if err := unsetOutput.validate(); err != nil {
+		return err
+	}
+
+	if unsetEngine != engineGopls && unsetEngine != engineAST {
+		return fmt.Errorf("unknown engine %q: must be %s or %s", unsetEngine, engineGopls, engineAST)
+	}
+
+	p, err := loadPolicy()
+	if err != nil {
+		return err
+	}
+
+	gopls, err := unsetGopls.options(p)
+	if err != nil {
+		return err
+	}
+
+	imports, err := goalias.Scan(cmd.Context(), scanOptions(args, unsetDiscovery), unsetPackages...)
+	if err != nil {
+		return fmt.Errorf("failed to find imports: %w", err)
+	}
+
+	// Everything below is a runtime failure rather than a usage error
+	cmd.SilenceUsage = true
+
+	var (
+		targets []goalias.Violation
+		aliased int
+	)
+	for _, imp := range imports {
+		switch imp.Name {
+		case "", "_", ".":
+			continue
+		}
+
+		name := goalias.PackageName(imp.Path)
+		if imp.Name != name {
+			aliased++
+			if !unsetForce {
+				continue
+			}
+		}
+
+		targets = append(targets, goalias.Violation{Import: imp, Rule: goalias.RuleUnaliased, Required: name})
+	}
+
+	if !unsetForce && aliased > 0 {
+		fmt.Fprintf(os.Stderr, "leaving %d non-redundant alias(es); use --force to remove them\n", aliased)
+	}
+
+	if len(targets) == 0 {
+		fmt.Println("No files need updating")
+		return nil
+	}
+
+	// Dropping a redundant name leaves every reference as it is, so the
+	// built-in engine handles those without starting gopls
+	engine := goalias.ASTEngine()
+	if unsetForce && aliased > 0 {
+		var closeEngine func()
+		if engine, closeEngine, err = newEngine(cmd.Context(), unsetEngine, gopls, unsetGopls.Trace); err != nil {
+			return err
+		}
+		defer closeEngine()
+	}
+
+	changes, err := goalias.Plan(cmd.Context(), targets, goalias.PlanOptions{
+		Engine:     engine,
+		OnConflict: goalias.ConflictSkip,
+		Progress: func(_, _ int, v goalias.Violation) {
+			if v.Name == v.Required {
+				fmt.Fprintf(os.Stderr, "Removing alias %s in %s\n", v.Name, relativePath(v.File))
+			} else {
+				fmt.Fprintf(os.Stderr, "Renaming %s to %s in %s\n", v.Name, v.Required, relativePath(v.File))
+			}
+		},
+		Notify: printNotice,
+	})
+	if err != nil {
+		return fmt.Errorf("%w; no files were modified", err)
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("No files need updating")
+		return nil
+	}
+
+	return applyChanges(cmd.Context(), changes, unsetOutput)
+}
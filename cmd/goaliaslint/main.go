@@ -0,0 +1,13 @@
+// Command goaliaslint checks import aliases against the goalias policy. It
+// runs standalone (goaliaslint ./...), applies suggested fixes with -fix, and
+// works as a go vet tool: go vet -vettool=$(which goaliaslint) ./...
+package main
+
+import (
+	"github.com/jackchuka/goalias/pkg/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
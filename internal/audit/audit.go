@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"sort"
+
+	"github.com/jackchuka/goalias/internal/discovery"
+)
+
+// Location is the position of an import spec
+type Location struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Usage is one name an import path is used under
+type Usage struct {
+	// Alias is the effective name: the declared alias, or the package name
+	// for unaliased imports
+	Alias string `json:"alias"`
+	// Count is the number of import specs using Alias
+	Count int `json:"count"`
+	// Explicit is the number of those specs that declare Alias explicitly
+	Explicit int `json:"explicit"`
+	// Examples are the first few locations using Alias
+	Examples []Location `json:"examples"`
+}
+
+// Entry summarises every name an import path is used under
+type Entry struct {
+	ImportPath string `json:"importPath"`
+	// PackageName is the declared name of the imported package
+	PackageName string `json:"packageName"`
+	// Usages are ordered by descending count, then alias
+	Usages []Usage `json:"usages"`
+	Total  int     `json:"total"`
+}
+
+// Inconsistent reports whether the path is used under more than one name
+func (e Entry) Inconsistent() bool {
+	return len(e.Usages) > 1
+}
+
+// Aliased reports whether any import of the path declares an alias
+func (e Entry) Aliased() bool {
+	for _, u := range e.Usages {
+		if u.Explicit > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Build groups imports by import path and effective alias, keeping up to
+// examples locations per alias. Blank imports are ignored since they bind no
+// name. Entries are ordered by import path.
+func Build(imports []discovery.ImportResult, examples int) []Entry {
+	byPath := make(map[string]*Entry)
+	usages := make(map[string]map[string]*Usage)
+
+	for _, imp := range imports {
+		if imp.Alias == "_" {
+			continue
+		}
+
+		entry, ok := byPath[imp.ImportPath]
+		if !ok {
+			entry = &Entry{
+				ImportPath:  imp.ImportPath,
+				PackageName: discovery.DefaultAlias(imp.ImportPath),
+			}
+			byPath[imp.ImportPath] = entry
+			usages[imp.ImportPath] = make(map[string]*Usage)
+		}
+
+		usage, ok := usages[imp.ImportPath][imp.Alias]
+		if !ok {
+			usage = &Usage{Alias: imp.Alias}
+			usages[imp.ImportPath][imp.Alias] = usage
+		}
+
+		usage.Count++
+		if imp.Info != nil && imp.Info.Alias != "" {
+			usage.Explicit++
+		}
+		if len(usage.Examples) < examples && imp.Info != nil {
+			usage.Examples = append(usage.Examples, Location{
+				File:   imp.File,
+				Line:   imp.Info.Position.Line,
+				Column: imp.Info.Position.Column,
+			})
+		}
+		entry.Total++
+	}
+
+	entries := make([]Entry, 0, len(byPath))
+	for path, entry := range byPath {
+		for _, u := range usages[path] {
+			entry.Usages = append(entry.Usages, *u)
+		}
+		sort.Slice(entry.Usages, func(i, j int) bool {
+			a, b := entry.Usages[i], entry.Usages[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Alias < b.Alias
+		})
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ImportPath < entries[j].ImportPath
+	})
+
+	return entries
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"go/token"
+	"reflect"
+	"testing"
+
+	"github.com/jackchuka/goalias/internal/discovery"
+	"github.com/jackchuka/goalias/internal/discovery/ast"
+)
+
+func imp(file string, line int, path, declared string) discovery.ImportResult {
+	alias := declared
+	if alias == "" {
+		alias = discovery.DefaultAlias(path)
+	}
+
+	return discovery.ImportResult{
+		File:       file,
+		ImportPath: path,
+		Alias:      alias,
+		Info: &ast.ImportInfo{
+			Position: token.Position{Filename: file, Line: line, Column: 2},
+			Path:     path,
+			Alias:    declared,
+			Found:    true,
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	imports := []discovery.ImportResult{
+		imp("a.go", 3, "net/http", ""),
+		imp("b.go", 4, "net/http", "nethttp"),
+		imp("c.go", 5, "net/http", "nethttp"),
+		imp("d.go", 6, "net/http", "http"),
+		imp("a.go", 4, "strings", ""),
+		imp("a.go", 5, "embed", "_"),
+	}
+
+	entries := Build(imports, 1)
+
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+
+	httpEntry := entries[0]
+	if httpEntry.ImportPath != "net/http" || httpEntry.PackageName != "http" || httpEntry.Total != 4 {
+		t.Errorf("unexpected entry %+v", httpEntry)
+	}
+
+	expected := []Usage{
+		{Alias: "http", Count: 2, Explicit: 1, Examples: []Location{{File: "a.go", Line: 3, Column: 2}}},
+		{Alias: "nethttp", Count: 2, Explicit: 2, Examples: []Location{{File: "b.go", Line: 4, Column: 2}}},
+	}
+	if !reflect.DeepEqual(httpEntry.Usages, expected) {
+		t.Errorf("expected usages %+v, got %+v", expected, httpEntry.Usages)
+	}
+
+	if !httpEntry.Inconsistent() || !httpEntry.Aliased() {
+		t.Errorf("expected net/http to be aliased and inconsistent")
+	}
+
+	stringsEntry := entries[1]
+	if stringsEntry.ImportPath != "strings" || stringsEntry.Inconsistent() || stringsEntry.Aliased() {
+		t.Errorf("expected strings to be consistent and unaliased, got %+v", stringsEntry)
+	}
+}
+
+func TestBuildEmpty(t *testing.T) {
+	if entries := Build(nil, 3); len(entries) != 0 {
+		t.Errorf("expected no entries, got %+v", entries)
+	}
+}
@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"sort"
+	"unicode"
+)
+
+// Suggestion is the proposed canonical alias for an import path
+type Suggestion struct {
+	ImportPath string `json:"importPath"`
+	Alias      string `json:"alias"`
+	// Votes is the number of imports already using Alias
+	Votes int `json:"votes"`
+	// Total is the number of imports of the path that bind a name
+	Total int `json:"total"`
+	// Alternatives are the other names in use, most used first
+	Alternatives []string `json:"alternatives,omitempty"`
+}
+
+// Suggest proposes a canonical alias for each entry by majority vote. Ties go
+// to the name following Go conventions: lowercase, without underscores, and
+// not shadowing a standard library package, then to the package's own name,
+// then alphabetically. std maps standard library import paths to package
+// names. Dot imports do not vote.
+func Suggest(entries []Entry, std map[string]string) []Suggestion {
+	stdNames := make(map[string]bool, len(std))
+	for _, name := range std {
+		stdNames[name] = true
+	}
+
+	var suggestions []Suggestion
+	for _, e := range entries {
+		var candidates []Usage
+		total := 0
+		for _, u := range e.Usages {
+			if u.Alias == "." {
+				continue
+			}
+			candidates = append(candidates, u)
+			total += u.Count
+		}
+
+		if len(candidates) == 0 {
+			continue
+		}
+
+		_, isStd := std[e.ImportPath]
+		conventional := func(alias string) bool {
+			return isConventional(alias) && (isStd || !stdNames[alias])
+		}
+
+		sort.SliceStable(candidates, func(i, j int) bool {
+			a, b := candidates[i], candidates[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			if ca, cb := conventional(a.Alias), conventional(b.Alias); ca != cb {
+				return ca
+			}
+			if pa, pb := a.Alias == e.PackageName, b.Alias == e.PackageName; pa != pb {
+				return pa
+			}
+			return a.Alias < b.Alias
+		})
+
+		s := Suggestion{
+			ImportPath: e.ImportPath,
+			Alias:      candidates[0].Alias,
+			Votes:      candidates[0].Count,
+			Total:      total,
+		}
+		for _, c := range candidates[1:] {
+			s.Alternatives = append(s.Alternatives, c.Alias)
+		}
+
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions
+}
+
+// isConventional reports whether alias is lowercase letters and digits only
+func isConventional(alias string) bool {
+	if alias == "" {
+		return false
+	}
+	for _, r := range alias {
+		if !unicode.IsLower(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
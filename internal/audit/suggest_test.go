@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	std := map[string]string{
+		"errors":   "errors",
+		"net/http": "http",
+	}
+
+	tests := []struct {
+		name     string
+		entry    Entry
+		expected Suggestion
+	}{
+		{
+			name: "majority wins",
+			entry: Entry{
+				ImportPath:  "github.com/pkg/errors",
+				PackageName: "errors",
+				Usages: []Usage{
+					{Alias: "errors", Count: 1},
+					{Alias: "pkgerrors", Count: 3},
+				},
+			},
+			expected: Suggestion{ImportPath: "github.com/pkg/errors", Alias: "pkgerrors", Votes: 3, Total: 4, Alternatives: []string{"errors"}},
+		},
+		{
+			name: "tie prefers not shadowing the standard library",
+			entry: Entry{
+				ImportPath:  "github.com/pkg/errors",
+				PackageName: "errors",
+				Usages: []Usage{
+					{Alias: "errors", Count: 2},
+					{Alias: "pkgerrors", Count: 2},
+				},
+			},
+			expected: Suggestion{ImportPath: "github.com/pkg/errors", Alias: "pkgerrors", Votes: 2, Total: 4, Alternatives: []string{"errors"}},
+		},
+		{
+			name: "standard library package keeps its own name",
+			entry: Entry{
+				ImportPath:  "net/http",
+				PackageName: "http",
+				Usages: []Usage{
+					{Alias: "http", Count: 2},
+					{Alias: "nethttp", Count: 2},
+				},
+			},
+			expected: Suggestion{ImportPath: "net/http", Alias: "http", Votes: 2, Total: 4, Alternatives: []string{"nethttp"}},
+		},
+		{
+			name: "tie prefers lowercase without underscores",
+			entry: Entry{
+				ImportPath:  "example.com/proto/user",
+				PackageName: "user",
+				Usages: []Usage{
+					{Alias: "user_pb", Count: 1},
+					{Alias: "userPB", Count: 1},
+					{Alias: "userpb", Count: 1},
+				},
+			},
+			expected: Suggestion{ImportPath: "example.com/proto/user", Alias: "userpb", Votes: 1, Total: 3, Alternatives: []string{"userPB", "user_pb"}},
+		},
+		{
+			name: "tie between conventional names prefers the package name",
+			entry: Entry{
+				ImportPath:  "example.com/utils",
+				PackageName: "utils",
+				Usages: []Usage{
+					{Alias: "u", Count: 1},
+					{Alias: "utils", Count: 1},
+				},
+			},
+			expected: Suggestion{ImportPath: "example.com/utils", Alias: "utils", Votes: 1, Total: 2, Alternatives: []string{"u"}},
+		},
+		{
+			name: "dot imports do not vote",
+			entry: Entry{
+				ImportPath:  "example.com/dsl",
+				PackageName: "dsl",
+				Usages: []Usage{
+					{Alias: ".", Count: 5},
+					{Alias: "dsl", Count: 1},
+				},
+			},
+			expected: Suggestion{ImportPath: "example.com/dsl", Alias: "dsl", Votes: 1, Total: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Suggest([]Entry{tt.entry}, std)
+			if len(result) != 1 {
+				t.Fatalf("expected 1 suggestion, got %d", len(result))
+			}
+			if !reflect.DeepEqual(result[0], tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, result[0])
+			}
+		})
+	}
+}
+
+func TestSuggestOnlyDotImports(t *testing.T) {
+	entries := []Entry{{
+		ImportPath:  "example.com/dsl",
+		PackageName: "dsl",
+		Usages:      []Usage{{Alias: ".", Count: 2}},
+	}}
+
+	if result := Suggest(entries, nil); len(result) != 0 {
+		t.Errorf("expected no suggestions, got %+v", result)
+	}
+}
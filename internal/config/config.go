@@ -0,0 +1,573 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the policy file discovered by Find
+const FileName = ".goalias.yaml"
+
+// Rule maps import paths to the alias they must be imported with. A rule
+// matches exactly one of: Path, an exact import path; Pattern, a glob where
+// * matches one path element, ** any number of elements and ? one character;
+// or Regex, a regular expression matched against the whole import path. For
+// Pattern and Regex rules, Alias is a template expanded with the captured
+// wildcards or groups as $1 or ${1} (and ${name} for named groups).
+type Rule struct {
+	Path    string `yaml:"path,omitempty"`
+	Pattern string `yaml:"pattern,omitempty"`
+	Regex   string `yaml:"regex,omitempty"`
+	Alias   string `yaml:"alias"`
+
+	re *regexp.Regexp
+}
+
+// match returns the alias the rule requires for importPath
+func (r Rule) match(importPath string) (string, bool) {
+	if r.Path != "" {
+		return r.Alias, r.Path == importPath
+	}
+
+	re := r.re
+	if re == nil {
+		// Rules built in code rather than parsed are compiled on demand
+		var err error
+		if re, err = r.regexp(); err != nil {
+			return "", false
+		}
+	}
+
+	submatches := re.FindStringSubmatchIndex(importPath)
+	if submatches == nil {
+		return "", false
+	}
+
+	return string(re.ExpandString(nil, r.Alias, importPath, submatches)), true
+}
+
+// regexp compiles the matcher of a Pattern or Regex rule
+func (r Rule) regexp() (*regexp.Regexp, error) {
+	if r.Pattern != "" {
+		return regexp.Compile(globToRegexp(r.Pattern))
+	}
+	return regexp.Compile("^(?:" + r.Regex + ")$")
+}
+
+// templateGroups returns the capture group numbers and names an alias
+// template refers to, following the syntax of regexp.Expand
+func templateGroups(template string) []string {
+	var groups []string
+
+	for {
+		i := strings.IndexByte(template, '$')
+		if i < 0 {
+			return groups
+		}
+		template = template[i+1:]
+
+		// $$ is a literal $
+		if strings.HasPrefix(template, "$") {
+			template = template[1:]
+			continue
+		}
+
+		brace := strings.HasPrefix(template, "{")
+		rest := template
+		if brace {
+			rest = rest[1:]
+		}
+
+		end := strings.IndexFunc(rest, func(r rune) bool {
+			return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		if end < 0 {
+			end = len(rest)
+		}
+		name := rest[:end]
+		rest = rest[end:]
+
+		// Anything else is copied literally
+		if name == "" || (brace && !strings.HasPrefix(rest, "}")) {
+			continue
+		}
+		if brace {
+			rest = rest[1:]
+		}
+
+		groups = append(groups, name)
+		template = rest
+	}
+}
+
+// hasGroup reports whether re has the capture group with the given number or
+// name; 0 is the whole match
+func hasGroup(re *regexp.Regexp, group string) bool {
+	if n, err := strconv.Atoi(group); err == nil {
+		return n <= re.NumSubexp()
+	}
+	return re.SubexpIndex(group) >= 0
+}
+
+// globToRegexp converts a glob to an anchored regular expression with a
+// capture group per wildcard
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString("(.+)")
+			i++
+		case glob[i] == '*':
+			b.WriteString("([^/]+)")
+		case glob[i] == '?':
+			b.WriteString("([^/])")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String()
+}
+
+// Override replaces rules for files below Dir (relative to the config file)
+type Override struct {
+	Dir   string `yaml:"dir"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Forbid bans an alias everywhere. It matches exactly one of: Alias, an exact
+// name such as "_" or "."; or Regex, a regular expression matched against
+// the whole alias.
+type Forbid struct {
+	Alias string `yaml:"alias,omitempty"`
+	Regex string `yaml:"regex,omitempty"`
+	// Except lists package names (the package clause, such as main) of files
+	// where the alias is allowed
+	Except []string `yaml:"except,omitempty"`
+	// Message explains the ban in reports
+	Message string `yaml:"message,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// matches reports whether f bans alias in a file of package pkgName
+func (f Forbid) matches(alias, pkgName string) bool {
+	for _, e := range f.Except {
+		if e == pkgName {
+			return false
+		}
+	}
+
+	if f.Alias != "" {
+		return f.Alias == alias
+	}
+
+	re := f.re
+	if re == nil {
+		var err error
+		if re, err = f.regexp(); err != nil {
+			return false
+		}
+	}
+	return re.MatchString(alias)
+}
+
+func (f Forbid) regexp() (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + f.Regex + ")$")
+}
+
+// Gopls configures the gopls process started to rename imports
+type Gopls struct {
+	// Path is the gopls binary. A relative path containing a separator is
+	// resolved against the directory of the config file.
+	Path string `yaml:"path,omitempty"`
+	// Args are extra arguments to gopls serve
+	Args []string `yaml:"args,omitempty"`
+	// Env overrides environment variables such as GOFLAGS
+	Env map[string]string `yaml:"env,omitempty"`
+	// Settings is the gopls settings block, such as buildFlags
+	Settings map[string]any `yaml:"settings,omitempty"`
+}
+
+// Config is the project-wide alias policy
+type Config struct {
+	Rules     []Rule     `yaml:"rules"`
+	Overrides []Override `yaml:"overrides,omitempty"`
+	// Unaliased lists import paths that must never be aliased
+	Unaliased []string `yaml:"unaliased,omitempty"`
+	// Forbidden lists aliases that must not be used for any import
+	Forbidden []Forbid `yaml:"forbidden,omitempty"`
+	// Gopls configures the language server used to rename imports
+	Gopls Gopls `yaml:"gopls,omitempty"`
+
+	// Path is the file the config was loaded from
+	Path string `yaml:"-"`
+	// Root is the directory override dirs are resolved against
+	Root string `yaml:"-"`
+}
+
+// Find walks up from startDir looking for FileName, stopping at the
+// directory containing go.mod. It returns an empty string if none is found.
+func Find(startDir string) (string, error) {
+	dir, err := filepath.Abs(startDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	for {
+		candidate := filepath.Join(dir, FileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return "", nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads and validates the config file at path
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	cfg.Path = absPath
+	cfg.Root = filepath.Dir(absPath)
+
+	if p := cfg.Gopls.Path; p != "" && !filepath.IsAbs(p) && strings.ContainsRune(filepath.ToSlash(p), '/') {
+		cfg.Gopls.Path = filepath.Join(cfg.Root, p)
+	}
+
+	return cfg, nil
+}
+
+// Discover finds and loads the config for startDir. It returns nil without
+// an error when the project has no config file.
+func Discover(startDir string) (*Config, error) {
+	path, err := Find(startDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if path == "" {
+		return nil, nil
+	}
+
+	return Load(path)
+}
+
+// Parse decodes and validates config data
+func Parse(data []byte) (*Config, error) {
+	cfg := &Config{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Encode writes cfg to w in the format read by Parse
+func Encode(w io.Writer, cfg *Config) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(cfg); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	return encoder.Close()
+}
+
+func (c *Config) validate() error {
+	if err := validateRules(c.Rules); err != nil {
+		return err
+	}
+
+	for i := range c.Overrides {
+		o := &c.Overrides[i]
+		if o.Dir == "" {
+			return fmt.Errorf("overrides[%d]: dir is required", i)
+		}
+		if err := validateRules(o.Rules); err != nil {
+			return fmt.Errorf("overrides[%d]: %w", i, err)
+		}
+	}
+
+	required := make(map[string]bool)
+	for _, p := range c.ImportPaths() {
+		required[p] = true
+	}
+
+	seen := make(map[string]bool)
+	for i, p := range c.Unaliased {
+		if p == "" {
+			return fmt.Errorf("unaliased[%d]: import path is required", i)
+		}
+		if seen[p] {
+			return fmt.Errorf("unaliased[%d]: duplicate entry for %s", i, p)
+		}
+		if required[p] {
+			return fmt.Errorf("unaliased[%d]: %s also has an alias rule", i, p)
+		}
+		seen[p] = true
+	}
+
+	for i := range c.Forbidden {
+		f := &c.Forbidden[i]
+
+		if (f.Alias == "") == (f.Regex == "") {
+			return fmt.Errorf("forbidden[%d]: exactly one of alias or regex is required", i)
+		}
+
+		if f.Regex != "" {
+			re, err := f.regexp()
+			if err != nil {
+				return fmt.Errorf("forbidden[%d]: invalid regex %s: %w", i, f.Regex, err)
+			}
+			f.re = re
+		}
+	}
+
+	for k := range c.Gopls.Env {
+		if k == "" || strings.Contains(k, "=") {
+			return fmt.Errorf("gopls.env: invalid variable name %q", k)
+		}
+	}
+
+	return nil
+}
+
+// validateRules checks rules and compiles their matchers in place
+func validateRules(rules []Rule) error {
+	seen := make(map[string]bool)
+
+	for i := range rules {
+		r := &rules[i]
+
+		matchers := 0
+		for _, m := range []string{r.Path, r.Pattern, r.Regex} {
+			if m != "" {
+				matchers++
+			}
+		}
+		if matchers != 1 {
+			return fmt.Errorf("rules[%d]: exactly one of path, pattern or regex is required", i)
+		}
+
+		key := r.Path + r.Pattern + r.Regex
+		if r.Alias == "" {
+			return fmt.Errorf("rules[%d]: alias is required for %s", i, key)
+		}
+		if r.Path == "" {
+			re, err := r.regexp()
+			if err != nil {
+				return fmt.Errorf("rules[%d]: invalid pattern %s: %w", i, key, err)
+			}
+			// regexp.Expand turns a missing group into an empty string
+			for _, group := range templateGroups(r.Alias) {
+				if !hasGroup(re, group) {
+					return fmt.Errorf("rules[%d]: alias %s for %s refers to capture group %s, which does not exist", i, r.Alias, key, group)
+				}
+			}
+			r.re = re
+		}
+
+		if seen[key] {
+			return fmt.Errorf("rules[%d]: duplicate rule for %s", i, key)
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// aliasFor applies the exact rules for importPath first, then the first
+// matching pattern or regex rule in file order
+func aliasFor(rules []Rule, importPath string) (string, bool) {
+	for _, r := range rules {
+		if r.Path != "" && r.Path == importPath {
+			return r.Alias, true
+		}
+	}
+
+	for _, r := range rules {
+		if r.Path != "" {
+			continue
+		}
+		if alias, ok := r.match(importPath); ok {
+			return alias, true
+		}
+	}
+
+	return "", false
+}
+
+// AliasFor returns the alias required for importPath in file. Rules from the
+// most specific override containing file take precedence over top-level rules.
+// Within a rule list, an exact path rule wins over pattern and regex rules,
+// which are tried in file order.
+func (c *Config) AliasFor(file, importPath string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	for _, o := range c.overridesFor(file) {
+		if alias, ok := aliasFor(o.Rules, importPath); ok {
+			return alias, true
+		}
+	}
+
+	return aliasFor(c.Rules, importPath)
+}
+
+// MustBeUnaliased reports whether importPath must be imported without an alias
+func (c *Config) MustBeUnaliased(importPath string) bool {
+	if c == nil {
+		return false
+	}
+
+	for _, p := range c.Unaliased {
+		if p == importPath {
+			return true
+		}
+	}
+	return false
+}
+
+// ForbiddenAlias returns the first forbidden rule banning alias in a file
+// whose package clause is pkgName
+func (c *Config) ForbiddenAlias(alias, pkgName string) (Forbid, bool) {
+	if c == nil {
+		return Forbid{}, false
+	}
+
+	for _, f := range c.Forbidden {
+		if f.matches(alias, pkgName) {
+			return f, true
+		}
+	}
+	return Forbid{}, false
+}
+
+// Empty reports whether the config has no rules of any kind
+func (c *Config) Empty() bool {
+	return c == nil || (len(c.ImportPaths()) == 0 && !c.HasPatterns() && len(c.Unaliased) == 0 && len(c.Forbidden) == 0)
+}
+
+// HasPatterns reports whether any rule matches by pattern or regex, in which
+// case ImportPaths does not cover every import the config applies to
+func (c *Config) HasPatterns() bool {
+	if c == nil {
+		return false
+	}
+
+	rules := append([]Rule(nil), c.Rules...)
+	for _, o := range c.Overrides {
+		rules = append(rules, o.Rules...)
+	}
+
+	for _, r := range rules {
+		if r.Pattern != "" || r.Regex != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportPaths returns every import path the config has an exact rule for
+func (c *Config) ImportPaths() []string {
+	if c == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var paths []string
+
+	add := func(rules []Rule) {
+		for _, r := range rules {
+			if r.Path != "" && !seen[r.Path] {
+				seen[r.Path] = true
+				paths = append(paths, r.Path)
+			}
+		}
+	}
+
+	add(c.Rules)
+	for _, o := range c.Overrides {
+		add(o.Rules)
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+// overridesFor returns the overrides containing file, most specific first
+func (c *Config) overridesFor(file string) []Override {
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return nil
+	}
+
+	var matched []Override
+	for _, o := range c.Overrides {
+		dir := filepath.Join(c.Root, filepath.FromSlash(o.Dir))
+		if isWithin(absFile, dir) {
+			matched = append(matched, o)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return len(filepath.Clean(matched[i].Dir)) > len(filepath.Clean(matched[j].Dir))
+	})
+
+	return matched
+}
+
+func isWithin(file, dir string) bool {
+	rel, err := filepath.Rel(dir, file)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
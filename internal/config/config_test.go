@@ -0,0 +1,589 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		expectErr bool
+		rules     int
+		overrides int
+	}{
+		{
+			name:    "empty config",
+			content: "",
+		},
+		{
+			name: "rules and overrides",
+			content: `rules:
+  - path: github.com/pkg/errors
+    alias: pkgerrors
+overrides:
+  - dir: internal/legacy
+    rules:
+      - path: github.com/pkg/errors
+        alias: errors
+`,
+			rules:     1,
+			overrides: 1,
+		},
+		{
+			name: "missing alias",
+			content: `rules:
+  - path: github.com/pkg/errors
+`,
+			expectErr: true,
+		},
+		{
+			name: "duplicate rule",
+			content: `rules:
+  - path: github.com/pkg/errors
+    alias: a
+  - path: github.com/pkg/errors
+    alias: b
+`,
+			expectErr: true,
+		},
+		{
+			name: "gopls settings",
+			content: `gopls:
+  path: /opt/gopls
+  args: [-remote=auto]
+  env:
+    GOFLAGS: -tags=integration
+  settings:
+    buildFlags: [-tags=integration]
+`,
+		},
+		{
+			name: "invalid gopls env name",
+			content: `gopls:
+  env:
+    "A=B": c
+`,
+			expectErr: true,
+		},
+		{
+			name: "override without dir",
+			content: `overrides:
+  - rules:
+      - path: github.com/pkg/errors
+        alias: errors
+`,
+			expectErr: true,
+		},
+		{
+			name: "pattern and regex rules",
+			content: `rules:
+  - pattern: k8s.io/api/*/v1
+    alias: ${1}v1
+  - regex: '.+/proto/(\w+)'
+    alias: ${1}pb
+`,
+			rules: 2,
+		},
+		{
+			name: "rule with path and pattern",
+			content: `rules:
+  - path: github.com/pkg/errors
+    pattern: github.com/pkg/*
+    alias: pkgerrors
+`,
+			expectErr: true,
+		},
+		{
+			name: "rule without matcher",
+			content: `rules:
+  - alias: pkgerrors
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid regex",
+			content: `rules:
+  - regex: '(unclosed'
+    alias: x
+`,
+			expectErr: true,
+		},
+		{
+			name: "unaliased and forbidden rules",
+			content: `unaliased:
+  - context
+forbidden:
+  - alias: .
+  - regex: '[a-z]'
+    except: [main]
+    message: no single letters
+`,
+		},
+		{
+			name: "unaliased path with alias rule",
+			content: `rules:
+  - path: context
+    alias: ctx
+unaliased:
+  - context
+`,
+			expectErr: true,
+		},
+		{
+			name: "forbidden without matcher",
+			content: `forbidden:
+  - message: nothing
+`,
+			expectErr: true,
+		},
+		{
+			name: "forbidden with invalid regex",
+			content: `forbidden:
+  - regex: '['
+`,
+			expectErr: true,
+		},
+		{
+			name: "alias template with existing groups",
+			content: `rules:
+  - pattern: github.com/acme/*/api/**
+    alias: ${1}api$$
+  - regex: 'k8s\.io/api/(?P<group>\w+)/(v\d+)'
+    alias: ${group}$2
+`,
+			rules: 2,
+		},
+		{
+			name: "alias template with missing group",
+			content: `rules:
+  - pattern: github.com/acme/*/api
+    alias: ${2}api
+`,
+			expectErr: true,
+		},
+		{
+			name: "alias template with missing named group",
+			content: `rules:
+  - regex: 'k8s\.io/api/(\w+)'
+    alias: ${group}
+`,
+			expectErr: true,
+		},
+		{
+			name: "alias template reading digits as a name",
+			content: `rules:
+  - pattern: github.com/acme/*
+    alias: $1v2
+`,
+			expectErr: true,
+		},
+		{
+			name:      "unknown field",
+			content:   "aliases: {}\n",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.content))
+
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if len(cfg.Rules) != tt.rules {
+				t.Errorf("expected %d rules, got %d", tt.rules, len(cfg.Rules))
+			}
+			if len(cfg.Overrides) != tt.overrides {
+				t.Errorf("expected %d overrides, got %d", tt.overrides, len(cfg.Overrides))
+			}
+		})
+	}
+}
+
+func TestLoadResolvesGoplsPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		expected func(dir string) string
+	}{
+		{
+			name:     "relative to config",
+			path:     "./bin/gopls",
+			expected: func(dir string) string { return filepath.Join(dir, "bin", "gopls") },
+		},
+		{
+			name:     "looked up in PATH",
+			path:     "gopls",
+			expected: func(string) string { return "gopls" },
+		},
+		{
+			name:     "absolute",
+			path:     "/opt/gopls",
+			expected: func(string) string { return "/opt/gopls" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, FileName)
+			if err := os.WriteFile(path, []byte("gopls:\n  path: "+tt.path+"\n"), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if cfg.Gopls.Path != tt.expected(dir) {
+				t.Errorf("expected %q, got %q", tt.expected(dir), cfg.Gopls.Path)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	cfg := &Config{
+		Rules: []Rule{
+			{Path: "github.com/pkg/errors", Alias: "pkgerrors"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `rules:
+  - path: github.com/pkg/errors
+    alias: pkgerrors
+`
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	parsed, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatalf("failed to parse encoded config: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Rules, cfg.Rules) {
+		t.Errorf("expected rules %+v, got %+v", cfg.Rules, parsed.Rules)
+	}
+}
+
+func TestAliasFor(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	cfg := &Config{
+		Root: root,
+		Rules: []Rule{
+			{Path: "github.com/pkg/errors", Alias: "pkgerrors"},
+			{Path: "example.com/utils", Alias: "myutils"},
+		},
+		Overrides: []Override{
+			{Dir: "internal", Rules: []Rule{{Path: "github.com/pkg/errors", Alias: "internalerrors"}}},
+			{Dir: "internal/legacy", Rules: []Rule{{Path: "github.com/pkg/errors", Alias: "errors"}}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		file       string
+		importPath string
+		expected   string
+		found      bool
+	}{
+		{
+			name:       "top-level rule",
+			file:       "/repo/cmd/main.go",
+			importPath: "github.com/pkg/errors",
+			expected:   "pkgerrors",
+			found:      true,
+		},
+		{
+			name:       "override applies",
+			file:       "/repo/internal/app/app.go",
+			importPath: "github.com/pkg/errors",
+			expected:   "internalerrors",
+			found:      true,
+		},
+		{
+			name:       "most specific override wins",
+			file:       "/repo/internal/legacy/old.go",
+			importPath: "github.com/pkg/errors",
+			expected:   "errors",
+			found:      true,
+		},
+		{
+			name:       "override falls back to top-level rule",
+			file:       "/repo/internal/legacy/old.go",
+			importPath: "example.com/utils",
+			expected:   "myutils",
+			found:      true,
+		},
+		{
+			name:       "directory prefix is not a match",
+			file:       "/repo/internalx/main.go",
+			importPath: "github.com/pkg/errors",
+			expected:   "pkgerrors",
+			found:      true,
+		},
+		{
+			name:       "no rule",
+			file:       "/repo/cmd/main.go",
+			importPath: "fmt",
+			found:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alias, found := cfg.AliasFor(filepath.FromSlash(tt.file), tt.importPath)
+			if found != tt.found {
+				t.Errorf("expected found %v, got %v", tt.found, found)
+			}
+			if alias != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, alias)
+			}
+		})
+	}
+}
+
+func TestAliasForPatterns(t *testing.T) {
+	cfg, err := Parse([]byte(`rules:
+  - pattern: k8s.io/api/*/v1
+    alias: ${1}v1
+  - regex: '.+/proto/(?:.+/)?(\w+)'
+    alias: ${1}pb
+  - pattern: example.com/**/proto/user
+    alias: shadowed
+  - path: example.com/proto/admin
+    alias: adminproto
+  - pattern: github.com/?/x
+    alias: short
+overrides:
+  - dir: legacy
+    rules:
+      - pattern: k8s.io/api/**
+        alias: legacyapi
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Root = filepath.FromSlash("/repo")
+
+	tests := []struct {
+		name       string
+		file       string
+		importPath string
+		expected   string
+		found      bool
+	}{
+		{
+			name:       "glob capture in template",
+			file:       "/repo/main.go",
+			importPath: "k8s.io/api/core/v1",
+			expected:   "corev1",
+			found:      true,
+		},
+		{
+			name:       "single star does not cross elements",
+			file:       "/repo/main.go",
+			importPath: "k8s.io/api/apps/x/v1",
+			found:      false,
+		},
+		{
+			name:       "regex capture in template",
+			file:       "/repo/main.go",
+			importPath: "example.com/svc/proto/v2/user",
+			expected:   "userpb",
+			found:      true,
+		},
+		{
+			name:       "first matching pattern wins",
+			file:       "/repo/main.go",
+			importPath: "example.com/a/b/proto/user",
+			expected:   "userpb",
+			found:      true,
+		},
+		{
+			name:       "exact rule wins over earlier patterns",
+			file:       "/repo/main.go",
+			importPath: "example.com/proto/admin",
+			expected:   "adminproto",
+			found:      true,
+		},
+		{
+			name:       "regex is anchored",
+			file:       "/repo/main.go",
+			importPath: "example.com/proto/user/extra-x",
+			found:      false,
+		},
+		{
+			name:       "question mark matches one character",
+			file:       "/repo/main.go",
+			importPath: "github.com/a/x",
+			expected:   "short",
+			found:      true,
+		},
+		{
+			name:       "override pattern wins over top-level rules",
+			file:       "/repo/legacy/old.go",
+			importPath: "k8s.io/api/core/v1",
+			expected:   "legacyapi",
+			found:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alias, found := cfg.AliasFor(filepath.FromSlash(tt.file), tt.importPath)
+			if found != tt.found {
+				t.Errorf("expected found %v, got %v", tt.found, found)
+			}
+			if alias != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, alias)
+			}
+		})
+	}
+
+	if !cfg.HasPatterns() {
+		t.Errorf("expected config to have patterns")
+	}
+	if paths := cfg.ImportPaths(); !reflect.DeepEqual(paths, []string{"example.com/proto/admin"}) {
+		t.Errorf("expected only exact paths, got %v", paths)
+	}
+}
+
+func TestAliasForUncompiledPattern(t *testing.T) {
+	cfg := &Config{Rules: []Rule{{Pattern: "example.com/*/api", Alias: "${1}api"}}}
+
+	alias, found := cfg.AliasFor("main.go", "example.com/users/api")
+	if !found || alias != "usersapi" {
+		t.Errorf("expected %q, got %q (found %v)", "usersapi", alias, found)
+	}
+}
+
+func TestForbiddenAlias(t *testing.T) {
+	cfg, err := Parse([]byte(`unaliased:
+  - errors
+forbidden:
+  - alias: _
+    except: [main]
+  - regex: '[a-z]'
+    message: no single letters
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		alias   string
+		pkgName string
+		message string
+		found   bool
+	}{
+		{name: "exact alias", alias: "_", pkgName: "lib", found: true},
+		{name: "allowed in excepted package", alias: "_", pkgName: "main", found: false},
+		{name: "regex match", alias: "x", pkgName: "lib", message: "no single letters", found: true},
+		{name: "regex is anchored", alias: "xy", pkgName: "lib", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forbid, found := cfg.ForbiddenAlias(tt.alias, tt.pkgName)
+			if found != tt.found {
+				t.Errorf("expected found %v, got %v", tt.found, found)
+			}
+			if forbid.Message != tt.message {
+				t.Errorf("expected message %q, got %q", tt.message, forbid.Message)
+			}
+		})
+	}
+
+	if !cfg.MustBeUnaliased("errors") || cfg.MustBeUnaliased("fmt") {
+		t.Errorf("expected only errors to be required unaliased")
+	}
+}
+
+func TestAliasForNilConfig(t *testing.T) {
+	var cfg *Config
+	if _, found := cfg.AliasFor("main.go", "fmt"); found {
+		t.Errorf("expected no alias from nil config")
+	}
+}
+
+func TestImportPaths(t *testing.T) {
+	cfg := &Config{
+		Rules: []Rule{
+			{Path: "github.com/pkg/errors", Alias: "pkgerrors"},
+		},
+		Overrides: []Override{
+			{Dir: "internal", Rules: []Rule{
+				{Path: "github.com/pkg/errors", Alias: "errors"},
+				{Path: "example.com/utils", Alias: "utils"},
+			}},
+		},
+	}
+
+	expected := []string{"example.com/utils", "github.com/pkg/errors"}
+	if result := cfg.ImportPaths(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("failed to create dirs: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/x\n"), 0644); err != nil {
+		t.Fatalf("failed to write go.mod: %v", err)
+	}
+
+	path, err := Find(nested)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "" {
+		t.Errorf("expected no config, got %q", path)
+	}
+
+	configFile := filepath.Join(root, FileName)
+	if err := os.WriteFile(configFile, []byte("rules: []\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	path, err = Find(nested)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != configFile {
+		t.Errorf("expected %q, got %q", configFile, path)
+	}
+
+	cfg, err := Discover(nested)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg == nil || cfg.Root != root {
+		t.Errorf("expected config rooted at %q, got %+v", root, cfg)
+	}
+}
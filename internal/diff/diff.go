@@ -0,0 +1,283 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OpKind is the kind of a line operation
+type OpKind int
+
+const (
+	Equal OpKind = iota
+	Delete
+	Insert
+)
+
+// Op is one line of an edit script
+type Op struct {
+	Kind OpKind
+	Line string
+}
+
+// Lines computes a shortest edit script turning a into b using Myers'
+// O(ND) algorithm
+func Lines(a, b []string) []Op {
+	n, m := len(a), len(b)
+	total := n + m
+	if total == 0 {
+		return nil
+	}
+
+	// v[k+offset] is the furthest x reached on diagonal k; trace keeps a
+	// copy of v per edit distance for backtracking
+	offset := total
+	v := make([]int, 2*total+2)
+	var trace [][]int
+
+	for d := 0; d <= total; d++ {
+		trace = append(trace, append([]int(nil), v...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset]
+			} else {
+				x = v[k-1+offset] + 1
+			}
+			y := x - k
+
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+offset] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset, d)
+			}
+		}
+	}
+
+	return nil
+}
+
+// backtrack walks the trace from the end to recover the edit script
+func backtrack(a, b []string, trace [][]int, offset, d int) []Op {
+	x, y := len(a), len(b)
+	var ops []Op
+
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[prevK+offset]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, Op{Kind: Equal, Line: a[x]})
+		}
+
+		if x == prevX {
+			y--
+			ops = append(ops, Op{Kind: Insert, Line: b[y]})
+		} else {
+			x--
+			ops = append(ops, Op{Kind: Delete, Line: a[x]})
+		}
+	}
+
+	for x > 0 && y > 0 {
+		x--
+		y--
+		ops = append(ops, Op{Kind: Equal, Line: a[x]})
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
+
+// Unified returns a unified diff of a and b with context lines around each
+// change, labelled with fromFile and toFile. It returns an empty string when
+// the contents are equal.
+func Unified(fromFile, toFile, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n", fromFile)
+	fmt.Fprintf(&sb, "+++ %s\n", toFile)
+	writeHunks(&sb, a, b, context)
+
+	return sb.String()
+}
+
+// GitPatch returns a diff of path in the format produced by git diff, which
+// git apply accepts. path should be relative to the repository root.
+func GitPatch(path, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "diff --git a/%s b/%s\n", path, path)
+	fmt.Fprintf(&sb, "--- a/%s\n", path)
+	fmt.Fprintf(&sb, "+++ b/%s\n", path)
+	writeHunks(&sb, a, b, context)
+
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func writeHunks(sb *strings.Builder, a, b string, context int) {
+	if context < 0 {
+		context = 0
+	}
+
+	ops := Lines(splitLines(a), splitLines(b))
+
+	// Index of each op's line in a and b
+	type position struct{ a, b int }
+	positions := make([]position, len(ops))
+	ai, bi := 0, 0
+	for i, op := range ops {
+		positions[i] = position{ai, bi}
+		switch op.Kind {
+		case Equal:
+			ai++
+			bi++
+		case Delete:
+			ai++
+		case Insert:
+			bi++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == Equal {
+			i++
+			continue
+		}
+
+		// Extend the hunk while changes are within 2*context lines
+		start := max(i-context, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == Equal {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = run
+		}
+
+		var aCount, bCount int
+		for _, op := range ops[start:end] {
+			if op.Kind != Insert {
+				aCount++
+			}
+			if op.Kind != Delete {
+				bCount++
+			}
+		}
+
+		fmt.Fprintf(sb, "@@ -%s +%s @@\n",
+			hunkRange(positions[start].a, aCount), hunkRange(positions[start].b, bCount))
+
+		for _, op := range ops[start:end] {
+			prefix := " "
+			switch op.Kind {
+			case Delete:
+				prefix = "-"
+			case Insert:
+				prefix = "+"
+			}
+
+			sb.WriteString(prefix)
+			sb.WriteString(op.Line)
+			if !strings.HasSuffix(op.Line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = end
+	}
+}
+
+// hunkRange formats a 0-based start and line count as a hunk header range.
+// An empty range names the line before it, as in GNU diff.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// ANSI colour codes used by Colorize
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorCyan  = "\x1b[36m"
+)
+
+// Colorize adds terminal colours to a unified diff
+func Colorize(unified string) string {
+	lines := strings.SplitAfter(unified, "\n")
+
+	var sb strings.Builder
+	for _, l := range lines {
+		text := strings.TrimSuffix(l, "\n")
+		newline := l[len(text):]
+
+		var color string
+		switch {
+		case strings.HasPrefix(text, "diff --git "), strings.HasPrefix(text, "--- "), strings.HasPrefix(text, "+++ "):
+			color = colorBold
+		case strings.HasPrefix(text, "@@"):
+			color = colorCyan
+		case strings.HasPrefix(text, "-"):
+			color = colorRed
+		case strings.HasPrefix(text, "+"):
+			color = colorGreen
+		}
+
+		if color == "" || text == "" {
+			sb.WriteString(l)
+			continue
+		}
+		sb.WriteString(color + text + colorReset + newline)
+	}
+
+	return sb.String()
+}
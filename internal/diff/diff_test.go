@@ -0,0 +1,199 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        []string
+		b        []string
+		expected string
+	}{
+		{
+			name:     "both empty",
+			expected: "",
+		},
+		{
+			name:     "equal",
+			a:        []string{"a", "b"},
+			b:        []string{"a", "b"},
+			expected: " a  b",
+		},
+		{
+			name:     "insert",
+			a:        []string{"a", "c"},
+			b:        []string{"a", "b", "c"},
+			expected: " a +b  c",
+		},
+		{
+			name:     "delete",
+			a:        []string{"a", "b", "c"},
+			b:        []string{"a", "c"},
+			expected: " a -b  c",
+		},
+		{
+			name:     "replace",
+			a:        []string{"a", "b", "c"},
+			b:        []string{"a", "x", "c"},
+			expected: " a -b +x  c",
+		},
+		{
+			name:     "from empty",
+			b:        []string{"a"},
+			expected: "+a",
+		},
+		{
+			name:     "to empty",
+			a:        []string{"a"},
+			expected: "-a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parts []string
+			for _, op := range Lines(tt.a, tt.b) {
+				prefix := " "
+				switch op.Kind {
+				case Delete:
+					prefix = "-"
+				case Insert:
+					prefix = "+"
+				}
+				parts = append(parts, prefix+op.Line)
+			}
+
+			result := strings.Join(parts, " ")
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(1)\n\tfmt.Println(2)\n\tfmt.Println(3)\n\tfmt.Println(4)\n\tfmt.Println(5)\n\tfmt.Println(6)\n\tfmt.Println(7)\n\tfmt.Println(8)\n}\n"
+	b := strings.ReplaceAll(a, "fmt", "f")
+	b = strings.Replace(b, "import \"f\"", "import f \"fmt\"", 1)
+
+	expected := `--- a.go
++++ a.go
+@@ -1,14 +1,14 @@
+ package main
+ 
+-import "fmt"
++import f "fmt"
+ 
+ func main() {
+-	fmt.Println(1)
+-	fmt.Println(2)
+-	fmt.Println(3)
+-	fmt.Println(4)
+-	fmt.Println(5)
+-	fmt.Println(6)
+-	fmt.Println(7)
+-	fmt.Println(8)
++	f.Println(1)
++	f.Println(2)
++	f.Println(3)
++	f.Println(4)
++	f.Println(5)
++	f.Println(6)
++	f.Println(7)
++	f.Println(8)
+ }
+`
+
+	if result := Unified("a.go", "a.go", a, b, 3); result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+
+	if result := Unified("a.go", "a.go", a, a, 3); result != "" {
+		t.Errorf("expected empty diff for equal contents, got %q", result)
+	}
+}
+
+func TestUnifiedHunks(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, strings.Repeat("x", i))
+	}
+	a := strings.Join(lines, "\n") + "\n"
+
+	changed := append([]string(nil), lines...)
+	changed[1] = "two"
+	changed[17] = "eighteen"
+	changed = append(changed[:10], changed[11:]...)
+	b := strings.Join(changed, "\n") + "\n"
+
+	expected := `--- a
++++ b
+@@ -1,5 +1,5 @@
+ x
+-xx
++two
+ xxx
+ xxxx
+ xxxxx
+@@ -8,13 +8,12 @@
+ xxxxxxxx
+ xxxxxxxxx
+ xxxxxxxxxx
+-xxxxxxxxxxx
+ xxxxxxxxxxxx
+ xxxxxxxxxxxxx
+ xxxxxxxxxxxxxx
+ xxxxxxxxxxxxxxx
+ xxxxxxxxxxxxxxxx
+ xxxxxxxxxxxxxxxxx
+-xxxxxxxxxxxxxxxxxx
++eighteen
+ xxxxxxxxxxxxxxxxxxx
+ xxxxxxxxxxxxxxxxxxxx
+`
+
+	if result := Unified("a", "b", a, b, 3); result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestUnifiedContext(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n"
+	b := "1\n2\nthree\n4\n5\n"
+
+	expected := "--- a\n+++ b\n@@ -3,1 +3,1 @@\n-3\n+three\n"
+	if result := Unified("a", "b", a, b, 0); result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestUnifiedNoNewlineAtEOF(t *testing.T) {
+	expected := "--- a\n+++ b\n@@ -1,1 +1,1 @@\n-a\n\\ No newline at end of file\n+b\n\\ No newline at end of file\n"
+	if result := Unified("a", "b", "a", "b", 3); result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestGitPatch(t *testing.T) {
+	expected := "diff --git a/dir/a.go b/dir/a.go\n--- a/dir/a.go\n+++ b/dir/a.go\n@@ -1,1 +1,1 @@\n-a\n+b\n"
+	if result := GitPatch("dir/a.go", "a\n", "b\n", 3); result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
+
+func TestColorize(t *testing.T) {
+	input := "--- a\n+++ b\n@@ -1,1 +1,1 @@\n-a\n+b\n c\n"
+	expected := colorBold + "--- a" + colorReset + "\n" +
+		colorBold + "+++ b" + colorReset + "\n" +
+		colorCyan + "@@ -1,1 +1,1 @@" + colorReset + "\n" +
+		colorRed + "-a" + colorReset + "\n" +
+		colorGreen + "+b" + colorReset + "\n" +
+		" c\n"
+
+	if result := Colorize(input); result != expected {
+		t.Errorf("expected %q, got %q", expected, result)
+	}
+}
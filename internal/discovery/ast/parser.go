@@ -9,11 +9,30 @@ import (
 
 type ImportInfo struct {
 	Position token.Position
+	Path     string
 	Alias    string
 	Found    bool
+	// FilePackage is the package clause of the file containing the import
+	FilePackage string
 }
 
 func FindImportSpecInFile(filename, importPath string) (*ImportInfo, error) {
+	infos, err := FindImportSpecsInFile(filename, []string{importPath})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(infos) == 0 {
+		return &ImportInfo{Found: false}, nil
+	}
+
+	return infos[0], nil
+}
+
+// FindImportSpecsInFile parses filename once and returns every import spec
+// for any of importPaths, in source order. A path imported more than once
+// yields a spec for each import. Generated files yield no specs.
+func FindImportSpecsInFile(filename string, importPaths []string) ([]*ImportInfo, error) {
 	fileSet := token.NewFileSet()
 
 	file, err := parser.ParseFile(fileSet, filename, nil, parser.ParseComments)
@@ -22,26 +41,69 @@ func FindImportSpecInFile(filename, importPath string) (*ImportInfo, error) {
 	}
 
 	if isGeneratedFile(file) {
-		return &ImportInfo{Found: false}, nil
+		return nil, nil
+	}
+
+	wanted := make(map[string]bool, len(importPaths))
+	for _, p := range importPaths {
+		wanted[p] = true
 	}
 
+	var infos []*ImportInfo
 	for _, imp := range file.Imports {
 		impPath := strings.Trim(imp.Path.Value, `"`)
-		if impPath == importPath {
-			info := &ImportInfo{
-				Position: fileSet.Position(imp.Pos()),
-				Found:    true,
-			}
+		if !wanted[impPath] {
+			continue
+		}
 
-			if imp.Name != nil {
-				info.Alias = imp.Name.Name
-			}
+		info := &ImportInfo{
+			Position:    fileSet.Position(imp.Pos()),
+			Path:        impPath,
+			Found:       true,
+			FilePackage: file.Name.Name,
+		}
 
-			return info, nil
+		if imp.Name != nil {
+			info.Alias = imp.Name.Name
 		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// FindAllImportSpecsInFile returns every import spec in filename in source
+// order. Generated files yield no specs.
+func FindAllImportSpecsInFile(filename string) ([]*ImportInfo, error) {
+	fileSet := token.NewFileSet()
+
+	file, err := parser.ParseFile(fileSet, filename, nil, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	if isGeneratedFile(file) {
+		return nil, nil
+	}
+
+	infos := make([]*ImportInfo, 0, len(file.Imports))
+	for _, imp := range file.Imports {
+		info := &ImportInfo{
+			Position:    fileSet.Position(imp.Pos()),
+			Path:        strings.Trim(imp.Path.Value, `"`),
+			Found:       true,
+			FilePackage: file.Name.Name,
+		}
+
+		if imp.Name != nil {
+			info.Alias = imp.Name.Name
+		}
+
+		infos = append(infos, info)
 	}
 
-	return &ImportInfo{Found: false}, nil
+	return infos, nil
 }
 
 func isGeneratedFile(file *ast.File) bool {
@@ -235,3 +235,161 @@ func main() {}`,
 		})
 	}
 }
+
+func TestFindImportSpecsInFile(t *testing.T) {
+	content := `package main
+
+import (
+	"fmt"
+	myos "os"
+	"strings"
+)
+
+func main() {
+	fmt.Println(strings.ToUpper("hello"))
+	myos.Exit(0)
+}`
+
+	tmpFile, err := os.CreateTemp("", "multi.go")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := tmpFile.WriteString(content); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	_ = tmpFile.Close()
+
+	infos, err := FindImportSpecsInFile(tmpFile.Name(), []string{"os", "fmt", "net/http"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []ImportInfo{
+		{Path: "fmt", Alias: "", Found: true},
+		{Path: "os", Alias: "myos", Found: true},
+	}
+
+	if len(infos) != len(expected) {
+		t.Fatalf("expected %d specs, got %d", len(expected), len(infos))
+	}
+
+	for i, e := range expected {
+		if infos[i].Path != e.Path || infos[i].Alias != e.Alias || infos[i].Found != e.Found {
+			t.Errorf("spec %d: expected %+v, got %+v", i, e, *infos[i])
+		}
+		if infos[i].Position.Line != 4+i {
+			t.Errorf("spec %d: expected line %d, got %d", i, 4+i, infos[i].Position.Line)
+		}
+	}
+}
+
+func TestFindAllImportSpecsInFile(t *testing.T) {
+	content := `package main
+
+import (
+	"fmt"
+	myos "os"
+	_ "embed"
+	. "strings"
+	other "os"
+)
+
+func main() {
+	fmt.Println(ToUpper("hello"))
+	myos.Exit(0)
+	other.Exit(0)
+}`
+
+	tmpFile, err := os.CreateTemp("", "all.go")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := tmpFile.WriteString(content); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	_ = tmpFile.Close()
+
+	infos, err := FindAllImportSpecsInFile(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []ImportInfo{
+		{Path: "fmt", Alias: "", Found: true},
+		{Path: "os", Alias: "myos", Found: true},
+		{Path: "embed", Alias: "_", Found: true},
+		{Path: "strings", Alias: ".", Found: true},
+		{Path: "os", Alias: "other", Found: true},
+	}
+
+	if len(infos) != len(expected) {
+		t.Fatalf("expected %d specs, got %d", len(expected), len(infos))
+	}
+
+	for i, e := range expected {
+		if infos[i].Path != e.Path || infos[i].Alias != e.Alias || infos[i].Found != e.Found {
+			t.Errorf("spec %d: expected %+v, got %+v", i, e, *infos[i])
+		}
+		if infos[i].Position.Line != 4+i {
+			t.Errorf("spec %d: expected line %d, got %d", i, 4+i, infos[i].Position.Line)
+		}
+	}
+}
+
+func TestFindImportSpecsInFileDuplicates(t *testing.T) {
+	content := `package main
+
+import (
+	foo "os"
+	"fmt"
+	bar "os"
+)
+
+func main() {
+	fmt.Println()
+	foo.Exit(0)
+	bar.Exit(0)
+}`
+
+	tmpFile, err := os.CreateTemp("", "dup.go")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := tmpFile.WriteString(content); err != nil {
+		t.Fatalf("failed to write to temp file: %v", err)
+	}
+	_ = tmpFile.Close()
+
+	infos, err := FindImportSpecsInFile(tmpFile.Name(), []string{"os"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 specs, got %d", len(infos))
+	}
+	if infos[0].Alias != "foo" || infos[1].Alias != "bar" {
+		t.Errorf("expected aliases foo and bar, got %q and %q", infos[0].Alias, infos[1].Alias)
+	}
+
+	// The single-spec lookup still reports the first import
+	info, err := FindImportSpecInFile(tmpFile.Name(), "os")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Alias != "foo" {
+		t.Errorf("expected first spec, got alias %q", info.Alias)
+	}
+}
@@ -0,0 +1,176 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// packageNames caches the declared package name per import path. An empty
+// name records a path that could not be loaded.
+var packageNames = struct {
+	sync.Mutex
+	names map[string]string
+}{names: make(map[string]string)}
+
+// ResolvePackageNames loads the declared package clause of every import path
+// not already cached, using a single go list invocation
+func ResolvePackageNames(importPaths []string) error {
+	packageNames.Lock()
+	var missing []string
+	for _, p := range importPaths {
+		if _, ok := packageNames.names[p]; !ok && p != "" {
+			missing = append(missing, p)
+		}
+	}
+	packageNames.Unlock()
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	args := append([]string{"list", "-e", "-json=ImportPath,Name"}, missing...)
+	cmd := exec.Command("go", args...)
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	// go list -e reports unloadable packages in the output, so a failure
+	// here means go itself could not run; fall back to the heuristic
+	runErr := cmd.Run()
+
+	resolved := make(map[string]string, len(missing))
+	decoder := json.NewDecoder(&stdout)
+
+	for decoder.More() {
+		var pkg struct {
+			ImportPath string
+			Name       string
+		}
+		if err := decoder.Decode(&pkg); err != nil {
+			return fmt.Errorf("failed to decode package: %w", err)
+		}
+		resolved[pkg.ImportPath] = pkg.Name
+	}
+
+	packageNames.Lock()
+	defer packageNames.Unlock()
+
+	for _, p := range missing {
+		packageNames.names[p] = resolved[p]
+	}
+
+	if runErr != nil && len(resolved) == 0 {
+		return fmt.Errorf("go list failed: %w", runErr)
+	}
+
+	return nil
+}
+
+// RecordPackageName caches the declared name of importPath, for callers that
+// already know it from type information and need no go list invocation
+func RecordPackageName(importPath, name string) {
+	packageNames.Lock()
+	defer packageNames.Unlock()
+
+	packageNames.names[importPath] = name
+}
+
+// DefaultAlias returns the name an unaliased import of importPath is referred
+// to by: the package's declared name when it can be resolved, and the
+// InferDefaultAlias heuristic otherwise
+func DefaultAlias(importPath string) string {
+	_ = ResolvePackageNames([]string{importPath})
+
+	packageNames.Lock()
+	name := packageNames.names[importPath]
+	packageNames.Unlock()
+
+	if name != "" {
+		return name
+	}
+
+	return InferDefaultAlias(importPath)
+}
+
+// StandardPackages returns the name of every importable standard library
+// package keyed by import path. Internal and vendored packages are omitted.
+func StandardPackages() (map[string]string, error) {
+	cmd := exec.Command("go", "list", "-e", "-json=ImportPath,Name", "std")
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list failed: %w", err)
+	}
+
+	packages := make(map[string]string)
+	decoder := json.NewDecoder(&stdout)
+
+	for decoder.More() {
+		var pkg struct {
+			ImportPath string
+			Name       string
+		}
+		if err := decoder.Decode(&pkg); err != nil {
+			return nil, fmt.Errorf("failed to decode package: %w", err)
+		}
+
+		if isInternalPath(pkg.ImportPath) || pkg.Name == "main" {
+			continue
+		}
+		packages[pkg.ImportPath] = pkg.Name
+	}
+
+	return packages, nil
+}
+
+func isInternalPath(importPath string) bool {
+	for _, elem := range strings.Split(importPath, "/") {
+		if elem == "internal" || elem == "vendor" {
+			return true
+		}
+	}
+	return false
+}
+
+// InferDefaultAlias guesses a package name from its import path. It skips a
+// trailing major version element (/v5), drops a "go-" prefix and cuts at the
+// first character that is not valid in an identifier (yaml.v2, redis-go).
+func InferDefaultAlias(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	if len(parts) == 0 {
+		return ""
+	}
+
+	base := parts[len(parts)-1]
+	if len(parts) > 1 && isMajorVersion(base) {
+		base = parts[len(parts)-2]
+	}
+
+	base = strings.TrimPrefix(base, "go-")
+
+	if i := strings.IndexFunc(base, notIdentifier); i >= 0 {
+		base = base[:i]
+	}
+
+	return base
+}
+
+func isMajorVersion(s string) bool {
+	if !strings.HasPrefix(s, "v") {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+func notIdentifier(r rune) bool {
+	return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+}
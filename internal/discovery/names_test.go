@@ -0,0 +1,160 @@
+package discovery
+
+import (
+	"testing"
+)
+
+func TestInferDefaultAlias(t *testing.T) {
+	tests := []struct {
+		name       string
+		importPath string
+		expected   string
+	}{
+		{
+			name:       "simple package name",
+			importPath: "fmt",
+			expected:   "fmt",
+		},
+		{
+			name:       "package with path",
+			importPath: "github.com/user/repo",
+			expected:   "repo",
+		},
+		{
+			name:       "package with nested path",
+			importPath: "github.com/user/repo/subpackage",
+			expected:   "subpackage",
+		},
+		{
+			name:       "standard library package",
+			importPath: "encoding/json",
+			expected:   "json",
+		},
+		{
+			name:       "empty import path",
+			importPath: "",
+			expected:   "",
+		},
+		{
+			name:       "single slash",
+			importPath: "/",
+			expected:   "",
+		},
+		{
+			name:       "trailing slash",
+			importPath: "github.com/user/repo/",
+			expected:   "",
+		},
+		{
+			name:       "complex path with version",
+			importPath: "gopkg.in/yaml.v2",
+			expected:   "yaml",
+		},
+		{
+			name:       "major version suffix",
+			importPath: "github.com/jackc/pgx/v5",
+			expected:   "pgx",
+		},
+		{
+			name:       "go- prefix",
+			importPath: "github.com/go-redis/redis/v9",
+			expected:   "redis",
+		},
+		{
+			name:       "go- prefix on last element",
+			importPath: "github.com/example/go-cache",
+			expected:   "cache",
+		},
+		{
+			name:       "-go suffix",
+			importPath: "github.com/opentracing/opentracing-go",
+			expected:   "opentracing",
+		},
+		{
+			name:       "bare version element",
+			importPath: "v2",
+			expected:   "v2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := InferDefaultAlias(tt.importPath)
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDefaultAlias(t *testing.T) {
+	tests := []struct {
+		name       string
+		importPath string
+		expected   string
+	}{
+		{
+			name:       "standard library package",
+			importPath: "encoding/json",
+			expected:   "json",
+		},
+		{
+			name:       "dependency with versioned path",
+			importPath: "gopkg.in/yaml.v3",
+			expected:   "yaml",
+		},
+		{
+			name:       "unresolvable package falls back to heuristic",
+			importPath: "nonexistent/package/v2",
+			expected:   "package",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := DefaultAlias(tt.importPath)
+			if result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestResolvePackageNamesCaches(t *testing.T) {
+	if err := ResolvePackageNames([]string{"net/http", "nonexistent/package"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	packageNames.Lock()
+	name, ok := packageNames.names["net/http"]
+	_, missingCached := packageNames.names["nonexistent/package"]
+	packageNames.Unlock()
+
+	if !ok || name != "http" {
+		t.Errorf("expected net/http to be cached as %q, got %q", "http", name)
+	}
+	if !missingCached {
+		t.Errorf("expected unresolvable package to be cached")
+	}
+}
+
+func TestStandardPackages(t *testing.T) {
+	packages, err := StandardPackages()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if packages["net/http"] != "http" {
+		t.Errorf("expected net/http to be named http, got %q", packages["net/http"])
+	}
+
+	for path := range packages {
+		if isInternalPath(path) {
+			t.Errorf("expected internal package %s to be omitted", path)
+		}
+	}
+
+	if _, ok := packages["cmd/go"]; ok {
+		t.Errorf("expected commands to be omitted")
+	}
+}
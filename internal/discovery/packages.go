@@ -4,27 +4,147 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
+	"strings"
 )
 
 type Package struct {
-	ImportPath string
-	Dir        string
-	GoFiles    []string
+	ImportPath     string
+	Dir            string
+	GoFiles        []string
+	CgoFiles       []string
+	TestGoFiles    []string
+	XTestGoFiles   []string
+	IgnoredGoFiles []string
 }
 
-func ListPackages(patterns []string) ([]Package, error) {
+// Options widens the set of files scanned in each package
+type Options struct {
+	// Tests includes _test.go files of the package and its external test package
+	Tests bool
+	// AllBuildTags includes files excluded by build constraints
+	AllBuildTags bool
+
+	// Tags, GOOS and GOARCH form a matrix of build contexts whose file sets
+	// are unioned. An empty GOOS or GOARCH list uses the environment's value.
+	Tags   []string
+	GOOS   []string
+	GOARCH []string
+}
+
+// BuildContext is a single go list configuration. Empty fields inherit the
+// environment.
+type BuildContext struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string
+}
+
+// String describes the build context for error messages
+func (bc BuildContext) String() string {
+	var parts []string
+	if bc.GOOS != "" {
+		parts = append(parts, "GOOS="+bc.GOOS)
+	}
+	if bc.GOARCH != "" {
+		parts = append(parts, "GOARCH="+bc.GOARCH)
+	}
+	if len(bc.Tags) > 0 {
+		parts = append(parts, "tags="+strings.Join(bc.Tags, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// BuildContexts returns every GOOS/GOARCH combination of the matrix, or nil
+// when no matrix is configured
+func (o Options) BuildContexts() []BuildContext {
+	if !o.hasBuildMatrix() {
+		return nil
+	}
+
+	goosList := o.GOOS
+	if len(goosList) == 0 {
+		goosList = []string{""}
+	}
+
+	goarchList := o.GOARCH
+	if len(goarchList) == 0 {
+		goarchList = []string{""}
+	}
+
+	var contexts []BuildContext
+	for _, goos := range goosList {
+		for _, goarch := range goarchList {
+			contexts = append(contexts, BuildContext{GOOS: goos, GOARCH: goarch, Tags: o.Tags})
+		}
+	}
+
+	return contexts
+}
+
+func (o Options) hasBuildMatrix() bool {
+	return len(o.Tags) > 0 || len(o.GOOS) > 0 || len(o.GOARCH) > 0
+}
+
+// SourceFile is a Go file and the import path of the package containing it
+type SourceFile struct {
+	Path    string
+	Package string
+	// BuildIgnored is set for files excluded by build constraints in the
+	// default build context
+	BuildIgnored bool
+}
+
+// ListPackages runs go list for patterns. When build contexts are given, go
+// list runs once per context and the packages are merged, unioning and
+// de-duplicating their files.
+func ListPackages(patterns []string, contexts ...BuildContext) ([]Package, error) {
 	if len(patterns) == 0 {
 		patterns = []string{"./..."}
 	}
 
-	args := append([]string{"list", "-json"}, patterns...)
+	if len(contexts) == 0 {
+		return listPackages(patterns, BuildContext{})
+	}
+
+	var all [][]Package
+	for _, bc := range contexts {
+		packages, err := listPackages(patterns, bc)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, packages)
+	}
+
+	return mergePackages(all...), nil
+}
+
+func listPackages(patterns []string, bc BuildContext) ([]Package, error) {
+	args := []string{"list", "-json"}
+	if len(bc.Tags) > 0 {
+		args = append(args, "-tags="+strings.Join(bc.Tags, ","))
+	}
+	args = append(args, patterns...)
+
 	cmd := exec.Command("go", args...)
+	if bc.GOOS != "" || bc.GOARCH != "" {
+		cmd.Env = os.Environ()
+		if bc.GOOS != "" {
+			cmd.Env = append(cmd.Env, "GOOS="+bc.GOOS)
+		}
+		if bc.GOARCH != "" {
+			cmd.Env = append(cmd.Env, "GOARCH="+bc.GOARCH)
+		}
+	}
 
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 
 	if err := cmd.Run(); err != nil {
+		if bc.GOOS != "" || bc.GOARCH != "" || len(bc.Tags) > 0 {
+			return nil, fmt.Errorf("go list failed for %s: %w", bc, err)
+		}
 		return nil, fmt.Errorf("go list failed: %w", err)
 	}
 
@@ -42,13 +162,109 @@ func ListPackages(patterns []string) ([]Package, error) {
 	return packages, nil
 }
 
+// mergePackages unions the packages listed for several build contexts. A file
+// built in any context is removed from IgnoredGoFiles.
+func mergePackages(lists ...[]Package) []Package {
+	var merged []*Package
+	byPath := make(map[string]*Package)
+
+	for _, packages := range lists {
+		for _, pkg := range packages {
+			existing, ok := byPath[pkg.ImportPath]
+			if !ok {
+				p := pkg
+				byPath[pkg.ImportPath] = &p
+				merged = append(merged, &p)
+				continue
+			}
+
+			existing.GoFiles = unionFiles(existing.GoFiles, pkg.GoFiles)
+			existing.CgoFiles = unionFiles(existing.CgoFiles, pkg.CgoFiles)
+			existing.TestGoFiles = unionFiles(existing.TestGoFiles, pkg.TestGoFiles)
+			existing.XTestGoFiles = unionFiles(existing.XTestGoFiles, pkg.XTestGoFiles)
+			existing.IgnoredGoFiles = unionFiles(existing.IgnoredGoFiles, pkg.IgnoredGoFiles)
+		}
+	}
+
+	packages := make([]Package, 0, len(merged))
+	for _, pkg := range merged {
+		built := make(map[string]bool)
+		for _, files := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.TestGoFiles, pkg.XTestGoFiles} {
+			for _, f := range files {
+				built[f] = true
+			}
+		}
+
+		var ignored []string
+		for _, f := range pkg.IgnoredGoFiles {
+			if !built[f] {
+				ignored = append(ignored, f)
+			}
+		}
+		pkg.IgnoredGoFiles = ignored
+
+		packages = append(packages, *pkg)
+	}
+
+	return packages
+}
+
+// unionFiles appends the names in b missing from a
+func unionFiles(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	for _, f := range a {
+		seen[f] = true
+	}
+
+	for _, f := range b {
+		if !seen[f] {
+			seen[f] = true
+			a = append(a, f)
+		}
+	}
+
+	return a
+}
+
 func GetGoFilesFromPackages(packages []Package) []string {
 	var files []string
 
+	for _, f := range GetSourceFilesFromPackages(packages, Options{}) {
+		files = append(files, f.Path)
+	}
+
+	return files
+}
+
+// GetSourceFilesFromPackages returns the Go files of packages along with
+// their containing package. Files of the external test package are reported
+// under the package's import path with a _test suffix.
+func GetSourceFilesFromPackages(packages []Package, opts Options) []SourceFile {
+	var files []SourceFile
+
 	for _, pkg := range packages {
-		for _, goFile := range pkg.GoFiles {
-			fullPath := fmt.Sprintf("%s/%s", pkg.Dir, goFile)
-			files = append(files, fullPath)
+		add := func(names []string, importPath string, ignored bool) {
+			for _, name := range names {
+				fullPath := fmt.Sprintf("%s/%s", pkg.Dir, name)
+				files = append(files, SourceFile{Path: fullPath, Package: importPath, BuildIgnored: ignored})
+			}
+		}
+
+		add(pkg.GoFiles, pkg.ImportPath, false)
+		add(pkg.CgoFiles, pkg.ImportPath, false)
+
+		if opts.Tests {
+			add(pkg.TestGoFiles, pkg.ImportPath, false)
+			add(pkg.XTestGoFiles, pkg.ImportPath+"_test", false)
+		}
+
+		if opts.AllBuildTags {
+			for _, name := range pkg.IgnoredGoFiles {
+				if !opts.Tests && strings.HasSuffix(name, "_test.go") {
+					continue
+				}
+				add([]string{name}, pkg.ImportPath, true)
+			}
 		}
 	}
 
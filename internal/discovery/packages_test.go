@@ -1,6 +1,10 @@
 package discovery
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -92,6 +96,90 @@ func TestGetGoFilesFromPackages(t *testing.T) {
 	}
 }
 
+func TestGetSourceFilesFromPackages(t *testing.T) {
+	packages := []Package{
+		{
+			ImportPath:     "example.com/test",
+			Dir:            "/path/to/test",
+			GoFiles:        []string{"main.go"},
+			CgoFiles:       []string{"cgo.go"},
+			TestGoFiles:    []string{"main_test.go"},
+			XTestGoFiles:   []string{"export_test.go"},
+			IgnoredGoFiles: []string{"windows.go", "integration_test.go"},
+		},
+		{
+			ImportPath: "example.com/utils",
+			Dir:        "/path/to/utils",
+			GoFiles:    []string{"helper.go"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected []SourceFile
+	}{
+		{
+			name: "default options",
+			opts: Options{},
+			expected: []SourceFile{
+				{Path: "/path/to/test/main.go", Package: "example.com/test"},
+				{Path: "/path/to/test/cgo.go", Package: "example.com/test"},
+				{Path: "/path/to/utils/helper.go", Package: "example.com/utils"},
+			},
+		},
+		{
+			name: "tests",
+			opts: Options{Tests: true},
+			expected: []SourceFile{
+				{Path: "/path/to/test/main.go", Package: "example.com/test"},
+				{Path: "/path/to/test/cgo.go", Package: "example.com/test"},
+				{Path: "/path/to/test/main_test.go", Package: "example.com/test"},
+				{Path: "/path/to/test/export_test.go", Package: "example.com/test_test"},
+				{Path: "/path/to/utils/helper.go", Package: "example.com/utils"},
+			},
+		},
+		{
+			name: "all build tags without tests",
+			opts: Options{AllBuildTags: true},
+			expected: []SourceFile{
+				{Path: "/path/to/test/main.go", Package: "example.com/test"},
+				{Path: "/path/to/test/cgo.go", Package: "example.com/test"},
+				{Path: "/path/to/test/windows.go", Package: "example.com/test", BuildIgnored: true},
+				{Path: "/path/to/utils/helper.go", Package: "example.com/utils"},
+			},
+		},
+		{
+			name: "all build tags with tests",
+			opts: Options{Tests: true, AllBuildTags: true},
+			expected: []SourceFile{
+				{Path: "/path/to/test/main.go", Package: "example.com/test"},
+				{Path: "/path/to/test/cgo.go", Package: "example.com/test"},
+				{Path: "/path/to/test/main_test.go", Package: "example.com/test"},
+				{Path: "/path/to/test/export_test.go", Package: "example.com/test_test"},
+				{Path: "/path/to/test/windows.go", Package: "example.com/test", BuildIgnored: true},
+				{Path: "/path/to/test/integration_test.go", Package: "example.com/test", BuildIgnored: true},
+				{Path: "/path/to/utils/helper.go", Package: "example.com/utils"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetSourceFilesFromPackages(packages, tt.opts)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %d files, got %d", len(tt.expected), len(result))
+			}
+
+			for i, e := range tt.expected {
+				if result[i] != e {
+					t.Errorf("expected file at index %d to be %+v, got %+v", i, e, result[i])
+				}
+			}
+		})
+	}
+}
+
 func TestListPackages(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -154,3 +242,126 @@ func TestListPackagesWithInvalidPattern(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildContexts(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		expected []BuildContext
+	}{
+		{
+			name:     "no matrix",
+			opts:     Options{},
+			expected: nil,
+		},
+		{
+			name: "tags only",
+			opts: Options{Tags: []string{"integration"}},
+			expected: []BuildContext{
+				{Tags: []string{"integration"}},
+			},
+		},
+		{
+			name: "goos and goarch",
+			opts: Options{GOOS: []string{"linux", "windows"}, GOARCH: []string{"amd64", "arm64"}},
+			expected: []BuildContext{
+				{GOOS: "linux", GOARCH: "amd64"},
+				{GOOS: "linux", GOARCH: "arm64"},
+				{GOOS: "windows", GOARCH: "amd64"},
+				{GOOS: "windows", GOARCH: "arm64"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.opts.BuildContexts()
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMergePackages(t *testing.T) {
+	linux := []Package{
+		{
+			ImportPath:     "example.com/test",
+			Dir:            "/path/to/test",
+			GoFiles:        []string{"main.go", "main_linux.go"},
+			IgnoredGoFiles: []string{"main_windows.go", "main_darwin.go"},
+		},
+	}
+	windows := []Package{
+		{
+			ImportPath:     "example.com/test",
+			Dir:            "/path/to/test",
+			GoFiles:        []string{"main.go", "main_windows.go"},
+			IgnoredGoFiles: []string{"main_linux.go", "main_darwin.go"},
+		},
+		{
+			ImportPath: "example.com/winonly",
+			Dir:        "/path/to/winonly",
+			GoFiles:    []string{"win.go"},
+		},
+	}
+
+	result := mergePackages(linux, windows)
+
+	expected := []Package{
+		{
+			ImportPath:     "example.com/test",
+			Dir:            "/path/to/test",
+			GoFiles:        []string{"main.go", "main_linux.go", "main_windows.go"},
+			IgnoredGoFiles: []string{"main_darwin.go"},
+		},
+		{
+			ImportPath: "example.com/winonly",
+			Dir:        "/path/to/winonly",
+			GoFiles:    []string{"win.go"},
+		},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestListPackagesWithBuildContexts(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":          "module example.com/matrix\n\ngo 1.22\n",
+		"main.go":         "package matrix\n",
+		"main_linux.go":   "package matrix\n",
+		"main_windows.go": "package matrix\n",
+		"integration.go":  "//go:build integration\n\npackage matrix\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	t.Chdir(dir)
+
+	packages, err := ListPackages([]string{"./..."},
+		BuildContext{GOOS: "linux", Tags: []string{"integration"}},
+		BuildContext{GOOS: "windows", Tags: []string{"integration"}},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(packages) != 1 {
+		t.Fatalf("expected 1 package, got %d", len(packages))
+	}
+
+	goFiles := append([]string(nil), packages[0].GoFiles...)
+	sort.Strings(goFiles)
+	expected := []string{"integration.go", "main.go", "main_linux.go", "main_windows.go"}
+	if !reflect.DeepEqual(goFiles, expected) {
+		t.Errorf("expected GoFiles %v, got %v", expected, goFiles)
+	}
+	if len(packages[0].IgnoredGoFiles) != 0 {
+		t.Errorf("expected no ignored files, got %v", packages[0].IgnoredGoFiles)
+	}
+}
@@ -2,54 +2,161 @@ package discovery
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/jackchuka/goalias/internal/discovery/ast"
 )
 
 type ImportResult struct {
-	File     string
-	Location string
-	Alias    string
-	Info     *ast.ImportInfo
+	File       string
+	Location   string
+	ImportPath string
+	// Package is the import path of the package containing File
+	Package string
+	Alias   string
+	Info    *ast.ImportInfo
+	// BuildIgnored is set when File is excluded by build constraints
+	BuildIgnored bool
 }
 
 func FindImportsInFiles(patterns []string, importPath string) ([]ImportResult, error) {
-	packages, err := ListPackages(patterns)
+	return FindImports(patterns, []string{importPath}, Options{})
+}
+
+// FindImports lists packages once and parses each file once, returning every
+// import of any of importPaths ordered by file and then source position. opts
+// selects which files of each package are scanned.
+func FindImports(patterns []string, importPaths []string, opts Options) ([]ImportResult, error) {
+	files, err := sourceFiles(patterns, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list packages: %w", err)
+		return nil, err
 	}
 
-	files := GetGoFilesFromPackages(packages)
+	// Resolve real package names up front in one go list call; failures
+	// fall back to the heuristic in DefaultAlias
+	_ = ResolvePackageNames(importPaths)
+
 	results := make([]ImportResult, 0)
 
-	for _, file := range files {
-		info, err := ast.FindImportSpecInFile(file, importPath)
+	for _, f := range files {
+		infos, err := ast.FindImportSpecsInFile(f.Path, importPaths)
 		if err != nil {
 			continue
 		}
 
-		if !info.Found {
+		for _, info := range infos {
+			results = append(results, newImportResult(f, info))
+		}
+	}
+
+	return results, nil
+}
+
+// FindAllImports returns every import in the files selected by patterns and
+// opts, ordered by file and then source position
+func FindAllImports(patterns []string, opts Options) ([]ImportResult, error) {
+	files, err := sourceFiles(patterns, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	type fileImports struct {
+		file  SourceFile
+		infos []*ast.ImportInfo
+	}
+
+	var (
+		scanned []fileImports
+		paths   []string
+		seen    = make(map[string]bool)
+	)
+
+	for _, f := range files {
+		infos, err := ast.FindAllImportSpecsInFile(f.Path)
+		if err != nil {
 			continue
 		}
 
-		alias := info.Alias
-		if alias == "" {
-			alias = InferDefaultAlias(importPath)
+		for _, info := range infos {
+			if !seen[info.Path] {
+				seen[info.Path] = true
+				paths = append(paths, info.Path)
+			}
 		}
+		scanned = append(scanned, fileImports{file: f, infos: infos})
+	}
+
+	// Imports are only known after scanning, so names are resolved in one
+	// go list call afterwards
+	_ = ResolvePackageNames(paths)
 
-		location := fmt.Sprintf("%s:%d", file, info.Position.Line)
-		results = append(results, ImportResult{
-			File:     file,
-			Location: location,
-			Alias:    alias,
-			Info:     info,
-		})
+	results := make([]ImportResult, 0)
+	for _, s := range scanned {
+		for _, info := range s.infos {
+			results = append(results, newImportResult(s.file, info))
+		}
 	}
 
 	return results, nil
 }
 
+// sourceFiles lists the packages matching patterns and returns the files opts
+// selects from them
+func sourceFiles(patterns []string, opts Options) ([]SourceFile, error) {
+	packages, err := ListPackages(patterns, opts.BuildContexts()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	files := GetSourceFilesFromPackages(packages, opts)
+
+	if opts.hasBuildMatrix() {
+		if err := markBuildIgnored(patterns, files); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+func newImportResult(f SourceFile, info *ast.ImportInfo) ImportResult {
+	alias := info.Alias
+	if alias == "" {
+		alias = DefaultAlias(info.Path)
+	}
+
+	return ImportResult{
+		File:         f.Path,
+		Location:     fmt.Sprintf("%s:%d", f.Path, info.Position.Line),
+		ImportPath:   info.Path,
+		Package:      f.Package,
+		Alias:        alias,
+		Info:         info,
+		BuildIgnored: f.BuildIgnored,
+	}
+}
+
+// markBuildIgnored flags files that only build in a non-default context, since
+// tools loading the default configuration (such as gopls) cannot see them
+func markBuildIgnored(patterns []string, files []SourceFile) error {
+	packages, err := ListPackages(patterns)
+	if err != nil {
+		return fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	defaultFiles := make(map[string]bool)
+	for _, f := range GetSourceFilesFromPackages(packages, Options{Tests: true}) {
+		defaultFiles[f.Path] = true
+	}
+
+	for i := range files {
+		if !defaultFiles[files[i].Path] {
+			files[i].BuildIgnored = true
+		}
+	}
+
+	return nil
+}
+
 func GetPatterns(args []string) []string {
 	patterns := []string{"./..."}
 	if len(args) > 0 {
@@ -57,11 +164,3 @@ func GetPatterns(args []string) []string {
 	}
 	return patterns
 }
-
-func InferDefaultAlias(importPath string) string {
-	parts := strings.Split(importPath, "/")
-	if len(parts) == 0 {
-		return ""
-	}
-	return parts[len(parts)-1]
-}
@@ -1,69 +1,12 @@
 package discovery
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/jackchuka/goalias/internal/discovery/ast"
 )
 
-func TestInferDefaultAlias(t *testing.T) {
-	tests := []struct {
-		name       string
-		importPath string
-		expected   string
-	}{
-		{
-			name:       "simple package name",
-			importPath: "fmt",
-			expected:   "fmt",
-		},
-		{
-			name:       "package with path",
-			importPath: "github.com/user/repo",
-			expected:   "repo",
-		},
-		{
-			name:       "package with nested path",
-			importPath: "github.com/user/repo/subpackage",
-			expected:   "subpackage",
-		},
-		{
-			name:       "standard library package",
-			importPath: "encoding/json",
-			expected:   "json",
-		},
-		{
-			name:       "empty import path",
-			importPath: "",
-			expected:   "",
-		},
-		{
-			name:       "single slash",
-			importPath: "/",
-			expected:   "",
-		},
-		{
-			name:       "trailing slash",
-			importPath: "github.com/user/repo/",
-			expected:   "",
-		},
-		{
-			name:       "complex path with version",
-			importPath: "gopkg.in/yaml.v2",
-			expected:   "yaml.v2",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := InferDefaultAlias(tt.importPath)
-			if result != tt.expected {
-				t.Errorf("expected %q, got %q", tt.expected, result)
-			}
-		})
-	}
-}
-
 func TestGetPatterns(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -226,3 +169,34 @@ func TestImportResult(t *testing.T) {
 		t.Errorf("expected Info.Alias to be 'f', got %q", result.Info.Alias)
 	}
 }
+
+func TestFindAllImports(t *testing.T) {
+	result, err := FindAllImports([]string{"."}, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	found := false
+	for _, r := range result {
+		if r.Info == nil || !r.Info.Found {
+			t.Errorf("%s: expected import info", r.Location)
+			continue
+		}
+		if r.Alias == "" {
+			t.Errorf("%s: expected an effective alias for %s", r.Location, r.ImportPath)
+		}
+		if strings.HasSuffix(r.File, "scanner.go") && r.ImportPath == "github.com/jackchuka/goalias/internal/discovery/ast" {
+			found = true
+			if r.Alias != "ast" || r.Info.Alias != "" {
+				t.Errorf("expected unaliased ast import, got alias %q declared as %q", r.Alias, r.Info.Alias)
+			}
+			if r.Package != "github.com/jackchuka/goalias/internal/discovery" {
+				t.Errorf("expected containing package, got %q", r.Package)
+			}
+		}
+	}
+
+	if !found {
+		t.Errorf("expected scanner.go's import of the ast package to be found")
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -28,17 +30,159 @@ type Client struct {
 	cmd    *exec.Cmd
 	stdin  io.WriteCloser
 	stdout io.ReadCloser
-	stderr io.ReadCloser
 
 	requestID  int64
 	requests   map[any]chan *JSONRPCResponse
 	requestsMu sync.RWMutex
 
+	// writeMu serializes messages written by requests and by handlers
+	// replying to the server
+	writeMu sync.Mutex
+
+	handlersMu           sync.RWMutex
+	requestHandlers      map[string]RequestHandler
+	notificationHandlers map[string][]NotificationHandler
+
 	ctx    context.Context
 	cancel context.CancelFunc
 
 	initialized bool
 	rootURI     uri.URI
+	settings    map[string]any
+
+	// positionEncoding is how the server counts characters in positions,
+	// UTF-16 unless Initialize negotiates otherwise
+	positionEncoding protocol.PositionEncodingKind
+
+	loadTimeout    time.Duration
+	requestTimeout time.Duration
+	onProgress     func(Progress)
+	onLog          func(protocol.MessageType, string)
+
+	// traceMu serializes trace lines of messages read and written
+	traceMu sync.Mutex
+	trace   io.Writer
+
+	// progress holds the title of every work done progress the server has
+	// begun and not yet ended. begun is closed when the first one begins,
+	// and progressChanged is signalled whenever one begins or ends.
+	progressMu      sync.Mutex
+	progress        map[string]string
+	begun           chan struct{}
+	begunOnce       sync.Once
+	progressChanged chan struct{}
+	progressGrace   time.Duration
+	progressSettle  time.Duration
+
+	// disconnected is closed, with connErr set, once the server can no
+	// longer answer; every pending request then fails with connErr
+	disconnected   chan struct{}
+	connErr        error
+	disconnectOnce sync.Once
+
+	closeOnce  sync.Once
+	stderrTail *tailBuffer
+}
+
+// ErrServerExited is returned for requests to a server whose process has
+// exited or that closed the connection
+var ErrServerExited = errors.New("language server exited")
+
+// shutdownTimeout bounds the shutdown handshake in Close and the wait for the
+// process to exit afterwards
+const shutdownTimeout = 5 * time.Second
+
+// progressGrace is how long Initialize waits for the server to begin
+// reporting progress before assuming it reports none and is ready
+const progressGrace = 2 * time.Second
+
+// progressSettle is how long the server must begin no new progress after
+// ending the last one for its initial load to count as finished. gopls ends
+// one phase of the load before it begins the next.
+const progressSettle = 500 * time.Millisecond
+
+// stderrTailSize is how much of the end of the server's standard error is
+// quoted when it exits unexpectedly
+const stderrTailSize = 2048
+
+// Default timeouts used when Options leaves them zero
+const (
+	DefaultLoadTimeout    = 5 * time.Minute
+	DefaultRequestTimeout = 30 * time.Second
+)
+
+// Options configures the gopls process a Client starts
+type Options struct {
+	// Path is the gopls binary. Empty means gopls from $PATH.
+	Path string
+	// Args are extra arguments to gopls serve, such as -remote=auto or
+	// -logfile
+	Args []string
+	// Env overrides variables of the inherited environment, such as GOFLAGS
+	Env map[string]string
+	// Settings is the gopls settings block, sent as initializationOptions
+	// and in answer to workspace/configuration requests for "gopls"
+	Settings map[string]any
+
+	// LoadTimeout bounds the wait for the initial workspace load in
+	// Initialize. Zero means DefaultLoadTimeout.
+	LoadTimeout time.Duration
+	// RequestTimeout bounds each request. Zero means DefaultRequestTimeout.
+	RequestTimeout time.Duration
+	// Progress, if set, receives every work done progress report of the
+	// server. It runs on the goroutine reading from the server and must not
+	// block.
+	Progress func(Progress)
+
+	// Stderr receives the standard error of the server. Nil discards it.
+	Stderr io.Writer
+	// Trace, if set, receives every JSON-RPC message in both directions, one
+	// timestamped line each
+	Trace io.Writer
+	// Log, if set, receives the window/logMessage and window/showMessage
+	// notifications of the server. It must not block.
+	Log func(protocol.MessageType, string)
+}
+
+// Progress is a work done progress report of the server, such as the
+// initial workspace load
+type Progress struct {
+	// Kind is begin, report or end
+	Kind string
+	// Title is given when the progress begins and repeated in its reports
+	Title   string
+	Message string
+	// Percentage is nil when the server does not report one
+	Percentage *uint32
+}
+
+// command returns the gopls command line
+func (o Options) command() (string, []string) {
+	path := o.Path
+	if path == "" {
+		path = "gopls"
+	}
+	return path, append([]string{"serve"}, o.Args...)
+}
+
+// environ returns the process environment with Env applied, in a stable order
+func (o Options) environ() []string {
+	if len(o.Env) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(o.Env))
+	for k := range o.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Later entries win, so overrides follow the inherited environment
+	env := os.Environ()
+	for _, k := range keys {
+		env = append(env, k+"="+o.Env[k])
+	}
+	return env
 }
 
 // JSONRPCResponse represents a JSON-RPC 2.0 response
@@ -56,11 +200,45 @@ type JSONRPCError struct {
 	Data    any    `json:"data,omitempty"`
 }
 
-// NewClient creates a new LSP client connected to gopls
-func NewClient(rootPath string) (*Client, error) {
+func (e *JSONRPCError) Error() string {
+	return e.Message
+}
+
+// JSON-RPC 2.0 error codes sent in replies to the server
+const (
+	codeMethodNotFound = -32601
+	codeInternalError  = -32603
+)
+
+// message is any JSON-RPC 2.0 message read from the server. Responses carry
+// an ID and a result or error, requests carry an ID and a method, and
+// notifications carry only a method.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *JSONRPCError   `json:"error,omitempty"`
+}
+
+// RequestHandler answers a request sent by the server. The result is sent as
+// the response; a returned *JSONRPCError is sent as is and any other error as
+// an internal error.
+type RequestHandler func(params json.RawMessage) (any, error)
+
+// NotificationHandler receives a notification sent by the server. Handlers
+// run on the goroutine reading from the server and must not block.
+type NotificationHandler func(params json.RawMessage)
+
+// NewClient creates a new LSP client connected to a gopls process started
+// as configured by opts
+func NewClient(rootPath string, opts Options) (*Client, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	cmd := exec.CommandContext(ctx, "gopls", "serve")
+	name, args := opts.command()
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = opts.environ()
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -74,15 +252,20 @@ func NewClient(rootPath string) (*Client, error) {
 		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		cancel()
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	// os/exec drains stderr into the writers as it is produced, so gopls
+	// never blocks writing to a full pipe. The tail is kept to explain a
+	// crash.
+	stderrTail := &tailBuffer{size: stderrTailSize}
+	cmd.Stderr = stderrTail
+	if opts.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(stderrTail, opts.Stderr)
 	}
+	// Children of gopls holding its stderr must not block Close
+	cmd.WaitDelay = shutdownTimeout
 
 	if err := cmd.Start(); err != nil {
 		cancel()
-		return nil, fmt.Errorf("failed to start gopls: %w", err)
+		return nil, fmt.Errorf("failed to start %s: %w", name, err)
 	}
 
 	// Convert path to URI using the standard library
@@ -92,28 +275,231 @@ func NewClient(rootPath string) (*Client, error) {
 		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
-	rootURI := uri.File(absPath)
+	client := newClient(ctx, cancel, stdin, stdout)
+	client.cmd = cmd
+	client.stderrTail = stderrTail
+	client.rootURI = uri.File(absPath)
+	client.settings = opts.Settings
+	client.onProgress = opts.Progress
+	client.trace = opts.Trace
+	client.onLog = opts.Log
+	if opts.LoadTimeout > 0 {
+		client.loadTimeout = opts.LoadTimeout
+	}
+	if opts.RequestTimeout > 0 {
+		client.requestTimeout = opts.RequestTimeout
+	}
+
+	go client.waitForExit()
+
+	return client, nil
+}
 
+// newClient creates a client exchanging messages over stdin and stdout and
+// starts reading from the server
+func newClient(ctx context.Context, cancel context.CancelFunc, stdin io.WriteCloser, stdout io.ReadCloser) *Client {
 	client := &Client{
-		cmd:       cmd,
-		stdin:     stdin,
-		stdout:    stdout,
-		stderr:    stderr,
-		requestID: 0,
-		requests:  make(map[any]chan *JSONRPCResponse),
-		ctx:       ctx,
-		cancel:    cancel,
-		rootURI:   rootURI,
+		stdin:                stdin,
+		stdout:               stdout,
+		requests:             make(map[any]chan *JSONRPCResponse),
+		requestHandlers:      make(map[string]RequestHandler),
+		notificationHandlers: make(map[string][]NotificationHandler),
+		ctx:                  ctx,
+		cancel:               cancel,
+		loadTimeout:          DefaultLoadTimeout,
+		requestTimeout:       DefaultRequestTimeout,
+		positionEncoding:     protocol.PositionEncodingKindUTF16,
+		progress:             make(map[string]string),
+		begun:                make(chan struct{}),
+		progressChanged:      make(chan struct{}, 1),
+		progressGrace:        progressGrace,
+		progressSettle:       progressSettle,
+		disconnected:         make(chan struct{}),
 	}
 
-	// Start reading responses
-	go client.readResponses()
+	client.registerDefaultHandlers()
 
-	return client, nil
+	// Start reading messages
+	go client.readMessages()
+
+	return client
+}
+
+// HandleRequest sets the handler answering server requests for method,
+// replacing any default one
+func (c *Client) HandleRequest(method string, handler RequestHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+
+	c.requestHandlers[method] = handler
 }
 
-// Initialize performs LSP initialization
-func (c *Client) Initialize() error {
+// OnNotification adds a handler for server notifications of method, such as
+// window/logMessage or textDocument/publishDiagnostics
+func (c *Client) OnNotification(method string, handler NotificationHandler) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+
+	c.notificationHandlers[method] = append(c.notificationHandlers[method], handler)
+}
+
+// registerDefaultHandlers answers the requests gopls sends during a session.
+// Unanswered, gopls waits on them and falls back to default settings.
+func (c *Client) registerDefaultHandlers() {
+	c.HandleRequest("workspace/configuration", func(params json.RawMessage) (any, error) {
+		var p protocol.ConfigurationParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+
+		// One entry per requested item; null keeps the server's defaults
+		result := make([]any, len(p.Items))
+		for i, item := range p.Items {
+			if item.Section != nil && *item.Section == "gopls" && c.settings != nil {
+				result[i] = c.settings
+			}
+		}
+		return result, nil
+	})
+
+	// Dynamic registration is not advertised; progress is accepted so the
+	// server may report it
+	accept := func(json.RawMessage) (any, error) { return nil, nil }
+	c.HandleRequest("client/registerCapability", accept)
+	c.HandleRequest("client/unregisterCapability", accept)
+	c.HandleRequest("window/workDoneProgress/create", accept)
+	c.HandleRequest("window/showMessageRequest", accept)
+
+	c.OnNotification("$/progress", c.trackProgress)
+
+	logMessage := func(params json.RawMessage) {
+		var p struct {
+			Type    protocol.MessageType `json:"type"`
+			Message string               `json:"message"`
+		}
+		if c.onLog != nil && json.Unmarshal(params, &p) == nil {
+			c.onLog(p.Type, p.Message)
+		}
+	}
+	c.OnNotification("window/logMessage", logMessage)
+	c.OnNotification("window/showMessage", logMessage)
+
+	// Edits are collected from rename results and written by goalias itself,
+	// never applied on the server's behalf
+	c.HandleRequest("workspace/applyEdit", func(json.RawMessage) (any, error) {
+		return &protocol.ApplyWorkspaceEditResult{
+			Applied:       false,
+			FailureReason: ptr("goalias does not apply server-initiated edits"),
+		}, nil
+	})
+}
+
+// trackProgress records the work done progress the server begins and ends and
+// forwards each report to the progress callback
+func (c *Client) trackProgress(params json.RawMessage) {
+	var p struct {
+		Token json.RawMessage `json:"token"`
+		Value struct {
+			Kind       string  `json:"kind"`
+			Title      string  `json:"title"`
+			Message    string  `json:"message"`
+			Percentage *uint32 `json:"percentage"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return
+	}
+
+	token := string(p.Token)
+	report := Progress{Kind: p.Value.Kind, Title: p.Value.Title, Message: p.Value.Message, Percentage: p.Value.Percentage}
+
+	c.progressMu.Lock()
+	switch p.Value.Kind {
+	case "begin":
+		c.progress[token] = p.Value.Title
+		c.begunOnce.Do(func() { close(c.begun) })
+	case "report":
+		report.Title = c.progress[token]
+	case "end":
+		report.Title = c.progress[token]
+		delete(c.progress, token)
+	}
+	c.progressMu.Unlock()
+
+	if p.Value.Kind == "begin" || p.Value.Kind == "end" {
+		select {
+		case c.progressChanged <- struct{}{}:
+		default:
+		}
+	}
+
+	if c.onProgress != nil {
+		c.onProgress(report)
+	}
+}
+
+// waitForLoad blocks until the work done progress the server begins after
+// initialization has ended and none has begun for progressSettle, which
+// gopls reports for its initial workspace load. A server that begins no
+// progress within progressGrace is assumed to report none and to be ready.
+func (c *Client) waitForLoad(ctx context.Context) error {
+	timeout := time.NewTimer(c.loadTimeout)
+	defer timeout.Stop()
+
+	grace := time.NewTimer(c.progressGrace)
+	defer grace.Stop()
+
+	select {
+	case <-c.begun:
+	case <-grace.C:
+		return nil
+	case <-timeout.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.ctx.Done():
+		return fmt.Errorf("client closed")
+	case <-c.disconnected:
+		return c.connErr
+	}
+
+	for {
+		c.progressMu.Lock()
+		active := len(c.progress)
+		c.progressMu.Unlock()
+
+		// The settle window restarts whenever progress begins or ends
+		var (
+			settle  *time.Timer
+			settled <-chan time.Time
+		)
+		if active == 0 {
+			settle = time.NewTimer(c.progressSettle)
+			settled = settle.C
+		}
+
+		select {
+		case <-c.progressChanged:
+			if settle != nil {
+				settle.Stop()
+			}
+		case <-settled:
+			return nil
+		case <-timeout.C:
+			return fmt.Errorf("workspace load did not finish within %s", c.loadTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.ctx.Done():
+			return fmt.Errorf("client closed")
+		case <-c.disconnected:
+			return c.connErr
+		}
+	}
+}
+
+// Initialize performs LSP initialization and waits for the server to load the
+// workspace
+func (c *Client) Initialize(ctx context.Context) error {
 	if c.initialized {
 		return nil
 	}
@@ -129,6 +515,14 @@ func (c *Client) Initialize() error {
 	// cannot be populated programmatically. gopls honors rootUri identically.
 	params.RootURI = &c.rootURI //nolint:staticcheck // see comment above
 	params.Capabilities = protocol.ClientCapabilities{
+		// Offer byte offsets, which is how goalias counts characters;
+		// servers that ignore the offer count UTF-16 code units
+		General: &protocol.GeneralClientCapabilities{
+			PositionEncodings: []protocol.PositionEncodingKind{protocol.PositionEncodingKindUTF8},
+		},
+		Window: &protocol.WindowClientCapabilities{
+			WorkDoneProgress: ptr(true),
+		},
 		Workspace: &protocol.WorkspaceClientCapabilities{
 			WorkspaceEdit: &protocol.WorkspaceEditClientCapabilities{
 				DocumentChanges: ptr(true),
@@ -142,25 +536,41 @@ func (c *Client) Initialize() error {
 		},
 	}
 
+	if c.settings != nil {
+		options, err := json.Marshal(c.settings)
+		if err != nil {
+			return fmt.Errorf("failed to marshal gopls settings: %w", err)
+		}
+		params.InitializationOptions = options
+	}
+
 	var result protocol.InitializeResult
-	if err := c.sendRequest("initialize", params, &result); err != nil {
+	if err := c.sendRequest(ctx, "initialize", params, &result); err != nil {
 		return fmt.Errorf("initialize request failed: %w", err)
 	}
 
+	// Servers that leave the encoding out use UTF-16
+	if result.Capabilities.PositionEncoding != "" {
+		c.positionEncoding = result.Capabilities.PositionEncoding
+	}
+
 	// Send initialized notification
 	if err := c.sendNotification("initialized", &protocol.InitializedParams{}); err != nil {
 		return fmt.Errorf("initialized notification failed: %w", err)
 	}
 
-	// Add small delay to let gopls process the notification
-	time.Sleep(100 * time.Millisecond)
+	// Renames issued before the workspace is loaded see only part of it
+	if err := c.waitForLoad(ctx); err != nil {
+		return fmt.Errorf("failed to wait for workspace load: %w", err)
+	}
 
 	c.initialized = true
 	return nil
 }
 
-// Rename performs a rename operation
-func (c *Client) Rename(filePath string, line, character int, newName string) (*protocol.WorkspaceEdit, error) {
+// Rename performs a rename operation. Characters in the position and in the
+// returned edits count bytes, whatever encoding the server uses.
+func (c *Client) Rename(ctx context.Context, filePath string, line, character int, newName string) (*protocol.WorkspaceEdit, error) {
 	if !c.initialized {
 		return nil, fmt.Errorf("client not initialized")
 	}
@@ -171,6 +581,17 @@ func (c *Client) Rename(filePath string, line, character int, newName string) (*
 		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
+	utf16 := c.positionEncoding != protocol.PositionEncodingKindUTF8
+	if utf16 {
+		lines, err := fileLines(absPath)
+		if err != nil {
+			return nil, err
+		}
+		if line < len(lines) {
+			character = utf16Column(lines[line], character)
+		}
+	}
+
 	fileURI := uri.File(absPath)
 
 	// Create a custom params structure that matches what gopls expects
@@ -186,40 +607,108 @@ func (c *Client) Rename(filePath string, line, character int, newName string) (*
 	}
 
 	var result protocol.WorkspaceEdit
-	if err := c.sendRequest("textDocument/rename", params, &result); err != nil {
+	if err := c.sendRequest(ctx, "textDocument/rename", params, &result); err != nil {
 		return nil, fmt.Errorf("rename request failed: %w", err)
 	}
 
+	if utf16 {
+		return bytePositions(&result)
+	}
 	return &result, nil
 }
 
-// Close closes the LSP client
+// Close shuts the server down with the shutdown request and exit
+// notification, and kills the process if it has not exited within
+// shutdownTimeout. Closing a client whose server already exited only releases
+// its resources.
 func (c *Client) Close() error {
-	if c.cancel != nil {
-		c.cancel()
-	}
+	c.closeOnce.Do(func() {
+		if c.initialized && c.connected() {
+			ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			if err := c.sendRequest(ctx, "shutdown", nil, nil); err == nil {
+				_ = c.sendNotification("exit", nil)
+			}
+			cancel()
+		}
 
-	if c.stdin != nil {
-		_ = c.stdin.Close()
-	}
+		if c.stdin != nil {
+			_ = c.stdin.Close()
+		}
 
-	if c.cmd != nil {
-		_ = c.cmd.Wait()
-	}
+		if c.cmd != nil {
+			timeout := time.NewTimer(shutdownTimeout)
+			select {
+			case <-c.disconnected:
+			case <-timeout.C:
+			}
+			timeout.Stop()
+		}
+
+		// Kills the process if it is still running
+		if c.cancel != nil {
+			c.cancel()
+		}
+
+		if c.cmd != nil {
+			<-c.disconnected
+		}
+	})
 
 	return nil
 }
 
-// sendRequest sends a JSON-RPC request and waits for response
-func (c *Client) sendRequest(method string, params any, result any) error {
+// waitForExit reaps the server process and fails every pending request once
+// it has exited
+func (c *Client) waitForExit() {
+	err := c.cmd.Wait()
+
+	cause := ErrServerExited
+	if err != nil {
+		cause = fmt.Errorf("%w: %v", ErrServerExited, err)
+	}
+	if tail := c.stderrTail.String(); tail != "" {
+		cause = fmt.Errorf("%w; stderr:\n%s", cause, tail)
+	}
+
+	c.disconnect(cause)
+}
+
+// disconnect records why the server can no longer answer and wakes every
+// pending request
+func (c *Client) disconnect(err error) {
+	c.disconnectOnce.Do(func() {
+		c.connErr = err
+		close(c.disconnected)
+	})
+}
+
+// connected reports whether the server can still answer
+func (c *Client) connected() bool {
+	select {
+	case <-c.disconnected:
+		return false
+	default:
+		return true
+	}
+}
+
+// sendRequest sends a JSON-RPC request and waits for response until ctx is
+// done or the request timeout expires, when the server is told to cancel it
+func (c *Client) sendRequest(ctx context.Context, method string, params any, result any) error {
 	id := atomic.AddInt64(&c.requestID, 1)
 
+	if !c.connected() {
+		return fmt.Errorf("%s request failed: %w", method, c.connErr)
+	}
+
 	// Create request as a map to ensure proper JSON serialization
 	request := map[string]any{
 		"jsonrpc": "2.0",
 		"id":      id,
 		"method":  method,
-		"params":  params,
+	}
+	if params != nil {
+		request["params"] = params
 	}
 
 	// Create response channel
@@ -241,7 +730,7 @@ func (c *Client) sendRequest(method string, params any, result any) error {
 	}
 
 	// Wait for response with timeout
-	timeout := time.NewTimer(30 * time.Second)
+	timeout := time.NewTimer(c.requestTimeout)
 	defer timeout.Stop()
 
 	select {
@@ -258,19 +747,32 @@ func (c *Client) sendRequest(method string, params any, result any) error {
 
 		return nil
 	case <-timeout.C:
-		return fmt.Errorf("request timeout after 30 seconds for method %s", method)
+		c.cancelRequest(id)
+		return fmt.Errorf("request timeout after %s for method %s", c.requestTimeout, method)
+	case <-ctx.Done():
+		c.cancelRequest(id)
+		return fmt.Errorf("%s request cancelled: %w", method, ctx.Err())
+	case <-c.disconnected:
+		return fmt.Errorf("%s request failed: %w", method, c.connErr)
 	case <-c.ctx.Done():
 		return fmt.Errorf("context cancelled")
 	}
 }
 
+// cancelRequest tells the server to stop working on an abandoned request
+func (c *Client) cancelRequest(id int64) {
+	_ = c.sendNotification("$/cancelRequest", map[string]any{"id": id})
+}
+
 // sendNotification sends a JSON-RPC notification
 func (c *Client) sendNotification(method string, params any) error {
 	// Create notification as a map
 	notification := map[string]any{
 		"jsonrpc": "2.0",
 		"method":  method,
-		"params":  params,
+	}
+	if params != nil {
+		notification["params"] = params
 	}
 
 	return c.sendMessage(notification)
@@ -285,6 +787,11 @@ func (c *Client) sendMessage(message any) error {
 
 	content := fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(data), data)
 
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.traceMessage("-->", data)
+
 	if _, err := c.stdin.Write([]byte(content)); err != nil {
 		return fmt.Errorf("failed to write message: %w", err)
 	}
@@ -292,8 +799,22 @@ func (c *Client) sendMessage(message any) error {
 	return nil
 }
 
-// readResponses reads responses from the LSP server
-func (c *Client) readResponses() {
+// traceMessage records a message sent (-->) or received (<--) in the trace
+func (c *Client) traceMessage(direction string, data []byte) {
+	if c.trace == nil {
+		return
+	}
+
+	line := fmt.Sprintf("%s %s %s\n", time.Now().Format("2006-01-02T15:04:05.000000Z07:00"), direction, data)
+
+	c.traceMu.Lock()
+	defer c.traceMu.Unlock()
+
+	_, _ = io.WriteString(c.trace, line)
+}
+
+// readMessages reads messages from the LSP server and dispatches them
+func (c *Client) readMessages() {
 	reader := bufio.NewReader(c.stdout)
 
 	for {
@@ -306,10 +827,8 @@ func (c *Client) readResponses() {
 		// Read Content-Length header
 		line, err := reader.ReadString('\n')
 		if err != nil {
-			if err == io.EOF {
-				return
-			}
-			continue
+			c.stopReading(err)
+			return
 		}
 
 		if !strings.HasPrefix(line, "Content-Length:") {
@@ -325,45 +844,153 @@ func (c *Client) readResponses() {
 
 		// Read empty line
 		if _, err := reader.ReadString('\n'); err != nil {
-			continue
+			c.stopReading(err)
+			return
 		}
 
 		// Read message content
 		content := make([]byte, contentLength)
 		if _, err := io.ReadFull(reader, content); err != nil {
-			continue
+			c.stopReading(err)
+			return
 		}
 
-		// Parse JSON-RPC response
-		var response JSONRPCResponse
-		if err := json.Unmarshal(content, &response); err != nil {
+		c.traceMessage("<--", content)
+
+		var msg message
+		if err := json.Unmarshal(content, &msg); err != nil {
 			continue
 		}
 
-		// Handle response
-		c.handleResponse(&response)
+		c.dispatch(&msg)
 	}
 }
 
-// handleResponse handles a JSON-RPC response
-func (c *Client) handleResponse(response *JSONRPCResponse) {
-	// Normalize ID type - JSON unmarshaling might convert int64 to float64
-	normalizedID := response.ID
-	if f, ok := response.ID.(float64); ok && f == float64(int64(f)) {
-		normalizedID = int64(f)
+// stopReading handles the end of the server's output. The exit of a process
+// is reported by waitForExit with its status, so only a connection without
+// one is disconnected here.
+func (c *Client) stopReading(err error) {
+	if c.cmd != nil {
+		return
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+		err = errors.New("connection closed")
+	}
+	c.disconnect(fmt.Errorf("%w: %v", ErrServerExited, err))
+}
+
+// dispatch routes a message by kind: responses to the waiting request,
+// requests to their handler, and notifications to every hook for the method
+func (c *Client) dispatch(msg *message) {
+	hasID := len(msg.ID) > 0 && string(msg.ID) != "null"
+
+	switch {
+	case msg.Method != "" && hasID:
+		// Handlers may send messages themselves, so they must not block
+		// the reader
+		go c.handleRequest(msg)
+	case msg.Method != "":
+		c.handleNotification(msg)
+	case hasID:
+		c.handleResponse(msg)
+	}
+}
+
+// handleRequest answers a server request with its handler's result, or with
+// a method not found error when there is no handler
+func (c *Client) handleRequest(msg *message) {
+	c.handlersMu.RLock()
+	handler, ok := c.requestHandlers[msg.Method]
+	c.handlersMu.RUnlock()
+
+	response := map[string]any{
+		"jsonrpc": "2.0",
+		"id":      msg.ID,
+	}
+
+	if !ok {
+		response["error"] = &JSONRPCError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+		_ = c.sendMessage(response)
+		return
+	}
+
+	result, err := handler(msg.Params)
+	if err != nil {
+		var rpcErr *JSONRPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &JSONRPCError{Code: codeInternalError, Message: err.Error()}
+		}
+		response["error"] = rpcErr
+	} else {
+		response["result"] = result
+	}
+
+	_ = c.sendMessage(response)
+}
+
+// handleNotification calls every hook registered for the notification
+func (c *Client) handleNotification(msg *message) {
+	c.handlersMu.RLock()
+	handlers := c.notificationHandlers[msg.Method]
+	c.handlersMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg.Params)
+	}
+}
+
+// handleResponse delivers a response to the request waiting for it
+func (c *Client) handleResponse(msg *message) {
+	// Requests are sent with integer IDs
+	var id int64
+	if err := json.Unmarshal(msg.ID, &id); err != nil {
+		return
 	}
 
 	c.requestsMu.RLock()
-	responseChan, exists := c.requests[normalizedID]
+	responseChan, exists := c.requests[id]
 	c.requestsMu.RUnlock()
 
 	if !exists {
 		return
 	}
 
+	response := &JSONRPCResponse{
+		JSONRPC: msg.JSONRPC,
+		ID:      id,
+		Result:  msg.Result,
+		Error:   msg.Error,
+	}
+
 	select {
 	case responseChan <- response:
 	default:
 		// Channel full, ignore
 	}
 }
+
+// tailBuffer keeps the last size bytes written to it
+type tailBuffer struct {
+	mu   sync.Mutex
+	buf  []byte
+	size int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.size; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// String returns the kept bytes without surrounding whitespace
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return strings.TrimSpace(string(b.buf))
+}
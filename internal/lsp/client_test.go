@@ -0,0 +1,624 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.lsp.dev/protocol"
+)
+
+// fakeServer is the server end of a client connected over pipes
+type fakeServer struct {
+	client *Client
+	reader *bufio.Reader
+	writer io.WriteCloser
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := newClient(ctx, cancel, clientOut, clientIn)
+	client.initialized = true
+
+	t.Cleanup(func() {
+		cancel()
+		_ = serverOut.Close()
+		_ = clientOut.Close()
+	})
+
+	return &fakeServer{client: client, reader: bufio.NewReader(serverIn), writer: serverOut}
+}
+
+func (s *fakeServer) send(t *testing.T, msg string) {
+	t.Helper()
+
+	if _, err := fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(msg), msg); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+}
+
+func (s *fakeServer) receive(t *testing.T) map[string]any {
+	t.Helper()
+
+	received := make(chan map[string]any, 1)
+	failed := make(chan error, 1)
+
+	go func() {
+		header, err := s.reader.ReadString('\n')
+		if err != nil {
+			failed <- err
+			return
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+		if err != nil {
+			failed <- err
+			return
+		}
+		if _, err := s.reader.ReadString('\n'); err != nil {
+			failed <- err
+			return
+		}
+
+		content := make([]byte, length)
+		if _, err := io.ReadFull(s.reader, content); err != nil {
+			failed <- err
+			return
+		}
+
+		var msg map[string]any
+		if err := json.Unmarshal(content, &msg); err != nil {
+			failed <- err
+			return
+		}
+		received <- msg
+	}()
+
+	select {
+	case msg := <-received:
+		return msg
+	case err := <-failed:
+		t.Fatalf("failed to receive message: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for a message from the client")
+	}
+	return nil
+}
+
+func TestClientAnswersServerRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		request   string
+		result    string
+		errorCode float64
+	}{
+		{
+			name:    "configuration",
+			request: `{"jsonrpc":"2.0","id":"s1","method":"workspace/configuration","params":{"items":[{"section":"gopls"},{"section":"go"}]}}`,
+			result:  `[null,null]`,
+		},
+		{
+			name:    "register capability",
+			request: `{"jsonrpc":"2.0","id":7,"method":"client/registerCapability","params":{"registrations":[]}}`,
+			result:  `null`,
+		},
+		{
+			name:    "work done progress",
+			request: `{"jsonrpc":"2.0","id":8,"method":"window/workDoneProgress/create","params":{"token":"t"}}`,
+			result:  `null`,
+		},
+		{
+			name:    "apply edit",
+			request: `{"jsonrpc":"2.0","id":9,"method":"workspace/applyEdit","params":{"edit":{}}}`,
+			result:  `{"applied":false,"failureReason":"goalias does not apply server-initiated edits"}`,
+		},
+		{
+			name:      "unknown method",
+			request:   `{"jsonrpc":"2.0","id":10,"method":"custom/unknown"}`,
+			errorCode: codeMethodNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t)
+			server.send(t, tt.request)
+
+			reply := server.receive(t)
+
+			var request map[string]any
+			if err := json.Unmarshal([]byte(tt.request), &request); err != nil {
+				t.Fatalf("invalid request: %v", err)
+			}
+			if reply["id"] != request["id"] {
+				t.Errorf("expected reply to id %v, got %v", request["id"], reply["id"])
+			}
+
+			if tt.errorCode != 0 {
+				rpcErr, ok := reply["error"].(map[string]any)
+				if !ok || rpcErr["code"] != tt.errorCode {
+					t.Errorf("expected error code %v, got %v", tt.errorCode, reply)
+				}
+				return
+			}
+
+			result, err := json.Marshal(reply["result"])
+			if err != nil {
+				t.Fatalf("failed to marshal result: %v", err)
+			}
+			if _, ok := reply["result"]; !ok || string(result) != tt.result {
+				t.Errorf("expected result %s, got %v", tt.result, reply)
+			}
+		})
+	}
+}
+
+func TestClientHandleRequestOverridesDefault(t *testing.T) {
+	server := newFakeServer(t)
+	server.client.HandleRequest("workspace/configuration", func(json.RawMessage) (any, error) {
+		return nil, &JSONRPCError{Code: 42, Message: "nope"}
+	})
+
+	server.send(t, `{"jsonrpc":"2.0","id":1,"method":"workspace/configuration","params":{"items":[]}}`)
+
+	reply := server.receive(t)
+	rpcErr, ok := reply["error"].(map[string]any)
+	if !ok || rpcErr["code"] != float64(42) || rpcErr["message"] != "nope" {
+		t.Errorf("expected the handler's error, got %v", reply)
+	}
+}
+
+func TestClientNotificationHooks(t *testing.T) {
+	server := newFakeServer(t)
+
+	messages := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		server.client.OnNotification("window/logMessage", func(params json.RawMessage) {
+			var p struct{ Message string }
+			_ = json.Unmarshal(params, &p)
+			messages <- p.Message
+		})
+	}
+
+	server.send(t, `{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{}}`)
+	server.send(t, `{"jsonrpc":"2.0","method":"window/logMessage","params":{"type":3,"message":"loaded"}}`)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-messages:
+			if msg != "loaded" {
+				t.Errorf("unexpected message %q", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for hook %d", i+1)
+		}
+	}
+}
+
+func TestClientRoutesResponses(t *testing.T) {
+	server := newFakeServer(t)
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+
+	go func() {
+		var result string
+		err := server.client.sendRequest(context.Background(), "test/echo", "ping", &result)
+		done <- outcome{result, err}
+	}()
+
+	request := server.receive(t)
+	if request["method"] != "test/echo" {
+		t.Fatalf("unexpected request %v", request)
+	}
+
+	// Interleaved server messages must not be mistaken for the response
+	server.send(t, `{"jsonrpc":"2.0","method":"window/logMessage","params":{"type":3,"message":"busy"}}`)
+	server.send(t, `{"jsonrpc":"2.0","id":1,"method":"client/registerCapability","params":{"registrations":[]}}`)
+	if reply := server.receive(t); reply["id"] != float64(1) || reply["method"] != nil {
+		t.Fatalf("expected a reply to the server request, got %v", reply)
+	}
+
+	server.send(t, fmt.Sprintf(`{"jsonrpc":"2.0","id":%v,"result":"pong"}`, request["id"]))
+
+	select {
+	case out := <-done:
+		if out.err != nil || out.result != "pong" {
+			t.Errorf("expected pong, got %q (%v)", out.result, out.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the response")
+	}
+}
+
+func TestClientWaitsForWorkspaceLoad(t *testing.T) {
+	server := newFakeServer(t)
+
+	reports := make(chan Progress, 3)
+	server.client.onProgress = func(p Progress) { reports <- p }
+
+	done := make(chan error, 1)
+	go func() { done <- server.client.waitForLoad(context.Background()) }()
+
+	server.send(t, `{"jsonrpc":"2.0","method":"$/progress","params":{"token":"load","value":{"kind":"begin","title":"Setting up workspace","message":"Loading packages..."}}}`)
+	server.send(t, `{"jsonrpc":"2.0","method":"$/progress","params":{"token":"load","value":{"kind":"report","percentage":50}}}`)
+
+	for i := 0; i < 2; i++ {
+		if p := <-reports; p.Title != "Setting up workspace" {
+			t.Errorf("expected the title on every report, got %+v", p)
+		}
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("returned before the load ended: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	server.send(t, `{"jsonrpc":"2.0","method":"$/progress","params":{"token":"load","value":{"kind":"end","message":"Finished loading packages."}}}`)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the load to finish")
+	}
+	if p := <-reports; p.Kind != "end" || p.Title != "Setting up workspace" {
+		t.Errorf("unexpected end report %+v", p)
+	}
+}
+
+func TestClientLoadTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		begin   bool
+		wantErr bool
+	}{
+		{name: "no progress reported", begin: false, wantErr: false},
+		{name: "load never ends", begin: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeServer(t)
+
+			if tt.begin {
+				server.client.loadTimeout = 50 * time.Millisecond
+
+				began := make(chan struct{})
+				server.client.onProgress = func(Progress) { close(began) }
+				server.send(t, `{"jsonrpc":"2.0","method":"$/progress","params":{"token":1,"value":{"kind":"begin","title":"Setting up workspace"}}}`)
+				<-began
+			}
+
+			// A server reporting no progress must not cost the default
+			// load timeout
+			start := time.Now()
+			err := server.client.waitForLoad(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*progressGrace {
+				t.Errorf("took %s to give up on progress", elapsed)
+			}
+		})
+	}
+}
+
+func TestClientCancelsRequest(t *testing.T) {
+	server := newFakeServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.client.sendRequest(ctx, "textDocument/rename", nil, nil) }()
+
+	request := server.receive(t)
+	cancel()
+
+	notification := server.receive(t)
+	params, _ := notification["params"].(map[string]any)
+	if notification["method"] != "$/cancelRequest" || params["id"] != request["id"] {
+		t.Errorf("expected a cancellation of request %v, got %v", request["id"], notification)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the request to fail")
+	}
+}
+
+func TestClientTrace(t *testing.T) {
+	server := newFakeServer(t)
+
+	var trace strings.Builder
+	server.client.trace = &trace
+
+	server.send(t, `{"jsonrpc":"2.0","id":3,"method":"client/registerCapability","params":{"registrations":[]}}`)
+	server.receive(t)
+
+	lines := strings.Split(strings.TrimSuffix(trace.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 trace lines, got %q", trace.String())
+	}
+
+	for i, expected := range []string{
+		`<-- {"jsonrpc":"2.0","id":3,"method":"client/registerCapability","params":{"registrations":[]}}`,
+		`--> {"id":3,"jsonrpc":"2.0","result":null}`,
+	} {
+		timestamp, rest, _ := strings.Cut(lines[i], " ")
+		if _, err := time.Parse(time.RFC3339Nano, timestamp); err != nil {
+			t.Errorf("line %d: invalid timestamp %q: %v", i+1, timestamp, err)
+		}
+		if rest != expected {
+			t.Errorf("line %d: expected %s, got %s", i+1, expected, rest)
+		}
+	}
+}
+
+func TestClientLogMessages(t *testing.T) {
+	server := newFakeServer(t)
+
+	type logged struct {
+		typ     protocol.MessageType
+		message string
+	}
+	messages := make(chan logged, 2)
+	server.client.onLog = func(typ protocol.MessageType, message string) {
+		messages <- logged{typ, message}
+	}
+
+	server.send(t, `{"jsonrpc":"2.0","method":"window/logMessage","params":{"type":3,"message":"go/packages.Load #1"}}`)
+	server.send(t, `{"jsonrpc":"2.0","method":"window/showMessage","params":{"type":1,"message":"go list failed"}}`)
+
+	for _, expected := range []logged{
+		{protocol.MessageTypeInfo, "go/packages.Load #1"},
+		{protocol.MessageTypeError, "go list failed"},
+	} {
+		select {
+		case got := <-messages:
+			if got != expected {
+				t.Errorf("expected %+v, got %+v", expected, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", expected.message)
+		}
+	}
+}
+
+func TestClientCloseShutsDownServer(t *testing.T) {
+	server := newFakeServer(t)
+
+	closed := make(chan error, 1)
+	go func() { closed <- server.client.Close() }()
+
+	shutdown := server.receive(t)
+	if shutdown["method"] != "shutdown" {
+		t.Fatalf("expected a shutdown request, got %v", shutdown)
+	}
+	server.send(t, fmt.Sprintf(`{"jsonrpc":"2.0","id":%v,"result":null}`, shutdown["id"]))
+
+	if exit := server.receive(t); exit["method"] != "exit" || exit["id"] != nil {
+		t.Fatalf("expected an exit notification, got %v", exit)
+	}
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for Close")
+	}
+}
+
+func TestClientFailsPendingRequestsOnDisconnect(t *testing.T) {
+	server := newFakeServer(t)
+
+	done := make(chan error, 1)
+	go func() { done <- server.client.sendRequest(context.Background(), "textDocument/rename", nil, nil) }()
+
+	server.receive(t)
+	_ = server.writer.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrServerExited) {
+			t.Errorf("expected ErrServerExited, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the request to fail")
+	}
+
+	if err := server.client.sendRequest(context.Background(), "textDocument/rename", nil, nil); !errors.Is(err, ErrServerExited) {
+		t.Errorf("expected later requests to fail with ErrServerExited, got %v", err)
+	}
+}
+
+// fakeGoplsEnv makes the test binary act as a gopls that crashes on its first
+// message
+const fakeGoplsEnv = "GOALIAS_FAKE_GOPLS"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeGoplsEnv) == "crash" {
+		_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+		fmt.Fprintln(os.Stderr, "panic: boom")
+		os.Exit(2)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestClientDetectsServerExit(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Skipf("cannot locate the test binary: %v", err)
+	}
+
+	client, err := NewClient(t.TempDir(), Options{
+		Path: executable,
+		Env:  map[string]string{fakeGoplsEnv: "crash"},
+	})
+	if err != nil {
+		t.Fatalf("failed to start fake gopls: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	start := time.Now()
+	err = client.Initialize(context.Background())
+
+	if !errors.Is(err, ErrServerExited) {
+		t.Fatalf("expected ErrServerExited, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "exit status 2") || !strings.Contains(err.Error(), "panic: boom") {
+		t.Errorf("expected the exit status and stderr in the error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %s to notice the exit", elapsed)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{size: 8}
+	_, _ = b.Write([]byte("goroutine 1\n"))
+	_, _ = b.Write([]byte("main()\n"))
+
+	if got := b.String(); got != "main()" {
+		t.Errorf("expected the last 8 bytes, got %q", got)
+	}
+}
+
+func TestClientWaitsForEveryLoadPhase(t *testing.T) {
+	server := newFakeServer(t)
+
+	done := make(chan error, 1)
+	go func() { done <- server.client.waitForLoad(context.Background()) }()
+
+	// gopls ends one phase of its initial load before beginning the next
+	server.send(t, `{"jsonrpc":"2.0","method":"$/progress","params":{"token":"setup","value":{"kind":"begin","title":"Setting up workspace"}}}`)
+	server.send(t, `{"jsonrpc":"2.0","method":"$/progress","params":{"token":"setup","value":{"kind":"end"}}}`)
+
+	select {
+	case err := <-done:
+		t.Fatalf("returned after the first phase ended: %v", err)
+	case <-time.After(progressSettle / 5):
+	}
+
+	server.send(t, `{"jsonrpc":"2.0","method":"$/progress","params":{"token":"load","value":{"kind":"begin","title":"Loading packages"}}}`)
+
+	select {
+	case err := <-done:
+		t.Fatalf("returned while the second phase was running: %v", err)
+	case <-time.After(2 * progressSettle):
+	}
+
+	server.send(t, `{"jsonrpc":"2.0","method":"$/progress","params":{"token":"load","value":{"kind":"end"}}}`)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the load to finish")
+	}
+}
+
+func TestClientRenameConvertsUTF16Positions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	original := "package main\n\nfunc main() { fmt.Println(\"héllo wörld 😀\"); myutils.Helper() }\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	line := `func main() { fmt.Println("héllo wörld 😀"); myutils.Helper() }`
+	byteCol := strings.Index(line, "myutils")
+	// é, ö and 😀 take 2, 2 and 4 bytes but 1, 1 and 2 UTF-16 code units
+	utf16Col := byteCol - 4
+
+	server := newFakeServer(t)
+
+	type outcome struct {
+		edit *protocol.WorkspaceEdit
+		err  error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		edit, err := server.client.Rename(context.Background(), path, 2, byteCol, "u")
+		done <- outcome{edit, err}
+	}()
+
+	request := server.receive(t)
+	params, _ := request["params"].(map[string]any)
+	position, _ := params["position"].(map[string]any)
+	if position["character"] != float64(utf16Col) {
+		t.Errorf("expected character %d in UTF-16 code units, got %v", utf16Col, position["character"])
+	}
+
+	server.send(t, fmt.Sprintf(`{"jsonrpc":"2.0","id":%v,"result":{"documentChanges":[{"textDocument":{"uri":%q,"version":1},"edits":[{"range":{"start":{"line":2,"character":%d},"end":{"line":2,"character":%d}},"newText":"u"}]}]}}`,
+		request["id"], "file://"+path, utf16Col, utf16Col+len("myutils")))
+
+	var out outcome
+	select {
+	case out = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the rename")
+	}
+	if out.err != nil {
+		t.Fatalf("rename failed: %v", out.err)
+	}
+
+	changes := NewChangeSet()
+	if err := changes.Add(out.edit); err != nil {
+		t.Fatal(err)
+	}
+	prepared, err := changes.Prepare()
+	if err != nil {
+		t.Fatalf("failed to prepare: %v", err)
+	}
+
+	want := strings.Replace(original, "myutils", "u", 1)
+	if len(prepared) != 1 || string(prepared[0].Modified) != want {
+		t.Errorf("expected\n%s\ngot %+v", want, prepared)
+	}
+}
+
+func TestClientLoadFailsOnDisconnect(t *testing.T) {
+	server := newFakeServer(t)
+
+	done := make(chan error, 1)
+	go func() { done <- server.client.waitForLoad(context.Background()) }()
+
+	server.send(t, `{"jsonrpc":"2.0","method":"$/progress","params":{"token":"load","value":{"kind":"begin","title":"Setting up workspace"}}}`)
+	_ = server.writer.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrServerExited) {
+			t.Errorf("expected ErrServerExited, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the load to fail")
+	}
+}
@@ -0,0 +1,87 @@
+package lsp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// fileLines reads a file and splits it into lines
+func fileLines(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return strings.Split(string(content), "\n"), nil
+}
+
+// utf16Column converts a byte offset within line to UTF-16 code units
+func utf16Column(line string, col int) int {
+	units := 0
+	for i, r := range line {
+		if i >= col {
+			break
+		}
+		units += runeUnits(r)
+	}
+	return units
+}
+
+// byteColumn converts an offset in UTF-16 code units within line to bytes
+func byteColumn(line string, units int) int {
+	n := 0
+	for i, r := range line {
+		if n >= units {
+			return i
+		}
+		n += runeUnits(r)
+	}
+	return len(line)
+}
+
+// runeUnits is the number of UTF-16 code units encoding r
+func runeUnits(r rune) int {
+	if n := utf16.RuneLen(r); n > 0 {
+		return n
+	}
+	return 1
+}
+
+// bytePositions converts the UTF-16 positions of every text edit in edit to
+// byte offsets, reading each file it touches. The result holds plain
+// TextEdits keyed by URI.
+func bytePositions(edit *protocol.WorkspaceEdit) (*protocol.WorkspaceEdit, error) {
+	changes := NewChangeSet()
+	if err := changes.Add(edit); err != nil {
+		return nil, err
+	}
+
+	result := &protocol.WorkspaceEdit{Changes: make(map[uri.URI][]protocol.TextEdit)}
+	for _, path := range changes.Files() {
+		lines, err := fileLines(path)
+		if err != nil {
+			return nil, err
+		}
+
+		convert := func(p protocol.Position) protocol.Position {
+			if int(p.Line) < len(lines) {
+				p.Character = uint32(byteColumn(lines[p.Line], int(p.Character)))
+			}
+			return p
+		}
+
+		edits := make([]protocol.TextEdit, 0, len(changes.edits[path]))
+		for _, e := range changes.edits[path] {
+			e.Range.Start = convert(e.Range.Start)
+			e.Range.End = convert(e.Range.End)
+			edits = append(edits, e)
+		}
+		result.Changes[uri.File(path)] = edits
+	}
+
+	return result, nil
+}
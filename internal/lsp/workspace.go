@@ -3,25 +3,46 @@ package lsp
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
+	"github.com/jackchuka/goalias/internal/diff"
 	"go.lsp.dev/protocol"
 )
 
 // ApplyWorkspaceEdit applies a workspace edit to the filesystem
 func ApplyWorkspaceEdit(edit *protocol.WorkspaceEdit, preview bool) error {
+	changes := NewChangeSet()
+	if err := changes.Add(edit); err != nil {
+		return err
+	}
+	return changes.Apply(preview)
+}
+
+// ChangeSet collects the text edits of many workspace edits and applies them
+// to every file at once. Edits are validated against the current contents
+// before anything is written, and files written before a failure are
+// restored, so the tree is never left half-migrated.
+type ChangeSet struct {
+	edits map[string][]protocol.TextEdit
+}
+
+// NewChangeSet creates an empty ChangeSet
+func NewChangeSet() *ChangeSet {
+	return &ChangeSet{edits: make(map[string][]protocol.TextEdit)}
+}
+
+// Add merges the text edits of a workspace edit into the change set
+func (cs *ChangeSet) Add(edit *protocol.WorkspaceEdit) error {
 	if edit == nil {
 		return fmt.Errorf("workspace edit is nil")
 	}
 
 	// Handle changes map (deprecated but still used)
-	if edit.Changes != nil {
-		for uri, edits := range edit.Changes {
-			if err := applyTextEdits(string(uri), edits, preview); err != nil {
-				return fmt.Errorf("failed to apply changes to %s: %w", uri, err)
-			}
-		}
+	for uri, edits := range edit.Changes {
+		path := uriToFilePath(string(uri))
+		cs.edits[path] = append(cs.edits[path], edits...)
 	}
 
 	// Handle document changes (preferred method). In protocol v1.0.0
@@ -37,85 +58,246 @@ func ApplyWorkspaceEdit(edit *protocol.WorkspaceEdit, preview bool) error {
 		if err != nil {
 			return fmt.Errorf("failed to apply document changes to %s: %w", tde.TextDocument.URI, err)
 		}
-		if err := applyTextEdits(string(tde.TextDocument.URI), edits, preview); err != nil {
-			return fmt.Errorf("failed to apply document changes to %s: %w", tde.TextDocument.URI, err)
-		}
+		path := uriToFilePath(string(tde.TextDocument.URI))
+		cs.edits[path] = append(cs.edits[path], edits...)
 	}
 
 	return nil
 }
 
-// textEditsFromElements flattens the protocol v1.0.0 TextDocumentEditElement
-// union into plain TextEdits. gopls rename results are plain TextEdits;
-// AnnotatedTextEdit embeds one. SnippetTextEdit is not requested by goalias.
-func textEditsFromElements(elems []protocol.TextDocumentEditElement) ([]protocol.TextEdit, error) {
-	edits := make([]protocol.TextEdit, 0, len(elems))
-	for _, e := range elems {
-		switch v := e.(type) {
-		case *protocol.TextEdit:
-			edits = append(edits, *v)
-		case *protocol.AnnotatedTextEdit:
-			edits = append(edits, v.TextEdit)
-		default:
-			return nil, fmt.Errorf("unsupported text edit element type %T", e)
-		}
+// Files returns the paths of the files the change set modifies
+func (cs *ChangeSet) Files() []string {
+	files := make([]string, 0, len(cs.edits))
+	for path := range cs.edits {
+		files = append(files, path)
 	}
-	return edits, nil
+	sort.Strings(files)
+	return files
 }
 
-// applyTextEdits applies text edits to a file
-func applyTextEdits(uri string, edits []protocol.TextEdit, preview bool) error {
-	// Convert URI to file path
-	filePath := uriToFilePath(uri)
+// FileChange is the result of applying a change set to one file
+type FileChange struct {
+	Path     string
+	Original []byte
+	Modified []byte
+	Mode     os.FileMode
+}
 
-	// Read the file
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", filePath, err)
+// Prepare reads every file and applies its edits in memory. It fails without
+// side effects if any edit does not apply cleanly to the current contents.
+func (cs *ChangeSet) Prepare() ([]FileChange, error) {
+	var changes []FileChange
+
+	for _, path := range cs.Files() {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat file %s: %w", path, err)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+
+		edits := dedupeEdits(cs.edits[path])
+		if err := validateEdits(string(content), edits); err != nil {
+			return nil, fmt.Errorf("edits for %s do not apply cleanly: %w", path, err)
+		}
+
+		modified, err := applyEditsToContent(string(content), edits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply edits to %s: %w", path, err)
+		}
+
+		if modified == string(content) {
+			continue
+		}
+
+		changes = append(changes, FileChange{
+			Path:     path,
+			Original: content,
+			Modified: []byte(modified),
+			Mode:     stat.Mode().Perm(),
+		})
 	}
 
-	// Apply edits
-	modifiedContent, err := applyEditsToContent(string(content), edits)
+	return changes, nil
+}
+
+// Apply validates every edit and then either prints a diff of each file or
+// writes all files atomically, restoring the originals if any write fails
+func (cs *ChangeSet) Apply(preview bool) error {
+	changes, err := cs.Prepare()
 	if err != nil {
-		return fmt.Errorf("failed to apply edits: %w", err)
+		return err
 	}
 
 	if preview {
-		// Print diff-like output
-		fmt.Printf("--- %s\n", filePath)
-		fmt.Printf("+++ %s\n", filePath)
-
-		// Simple diff output (could be enhanced with proper diff library)
-		originalLines := strings.Split(string(content), "\n")
-		modifiedLines := strings.Split(modifiedContent, "\n")
-
-		for i, line := range originalLines {
-			if i < len(modifiedLines) {
-				if line != modifiedLines[i] {
-					fmt.Printf("-%s\n", line)
-					fmt.Printf("+%s\n", modifiedLines[i])
-				}
-			} else {
-				fmt.Printf("-%s\n", line)
+		for _, c := range changes {
+			fmt.Print(diff.Unified(c.Path, c.Path, string(c.Original), string(c.Modified), 3))
+		}
+		return nil
+	}
+
+	return WriteChanges(changes)
+}
+
+// WriteChanges stages every file in a temporary file next to it, then renames
+// them into place. If staging fails nothing is modified; if a rename fails
+// the files already replaced are restored.
+func WriteChanges(changes []FileChange) error {
+	staged := make([]string, 0, len(changes))
+	defer func() {
+		for _, tmp := range staged {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	for _, c := range changes {
+		tmp, err := stageFile(c.Path, c.Modified, c.Mode)
+		if err != nil {
+			return err
+		}
+		staged = append(staged, tmp)
+	}
+
+	for i, c := range changes {
+		if err := os.Rename(staged[i], c.Path); err != nil {
+			renameErr := fmt.Errorf("failed to write file %s: %w", c.Path, err)
+			if restoreErr := restoreChanges(changes[:i]); restoreErr != nil {
+				return fmt.Errorf("%w; %w", renameErr, restoreErr)
 			}
+			return renameErr
 		}
+	}
 
-		// Print any additional lines in modified content
-		for i := len(originalLines); i < len(modifiedLines); i++ {
-			fmt.Printf("+%s\n", modifiedLines[i])
+	staged = nil
+	return nil
+}
+
+// restoreChanges writes back the original contents of files already replaced
+func restoreChanges(changes []FileChange) error {
+	var failed []string
+
+	for _, c := range changes {
+		tmp, err := stageFile(c.Path, c.Original, c.Mode)
+		if err == nil {
+			err = os.Rename(tmp, c.Path)
+		}
+		if err != nil {
+			_ = os.Remove(tmp)
+			failed = append(failed, c.Path)
 		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to restore %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// stageFile writes content to a temporary file in the directory of path
+func stageFile(path string, content []byte, mode os.FileMode) (string, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".goalias-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to stage file %s: %w", path, err)
+	}
+
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to stage file %s: %w", path, err)
+	}
+
+	return tmp.Name(), nil
+}
+
+// dedupeEdits drops identical edits, which servers may report more than once
+// when a file belongs to several packages
+func dedupeEdits(edits []protocol.TextEdit) []protocol.TextEdit {
+	seen := make(map[protocol.TextEdit]bool, len(edits))
+	result := make([]protocol.TextEdit, 0, len(edits))
+
+	for _, e := range edits {
+		if !seen[e] {
+			seen[e] = true
+			result = append(result, e)
+		}
+	}
+
+	return result
+}
+
+// validateEdits checks that every edit range lies within content and that
+// no two edits overlap
+func validateEdits(content string, edits []protocol.TextEdit) error {
+	lines := strings.Split(content, "\n")
 
-		fmt.Println()
-	} else {
-		// Write the modified content back to the file
-		if err := os.WriteFile(filePath, []byte(modifiedContent), 0644); err != nil {
-			return fmt.Errorf("failed to write file %s: %w", filePath, err)
+	inBounds := func(p protocol.Position) bool {
+		return int(p.Line) < len(lines) && int(p.Character) <= len(lines[p.Line])
+	}
+
+	sorted := make([]protocol.TextEdit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return positionLess(sorted[i].Range.Start, sorted[j].Range.Start)
+	})
+
+	for i, e := range sorted {
+		if !inBounds(e.Range.Start) || !inBounds(e.Range.End) {
+			return fmt.Errorf("range %s is outside the file", formatRange(e.Range))
+		}
+		if positionLess(e.Range.End, e.Range.Start) {
+			return fmt.Errorf("range %s ends before it starts", formatRange(e.Range))
+		}
+		if i > 0 && positionLess(e.Range.Start, sorted[i-1].Range.End) {
+			return fmt.Errorf("range %s overlaps %s", formatRange(e.Range), formatRange(sorted[i-1].Range))
 		}
 	}
 
 	return nil
 }
 
+func positionLess(a, b protocol.Position) bool {
+	if a.Line != b.Line {
+		return a.Line < b.Line
+	}
+	return a.Character < b.Character
+}
+
+// formatRange formats a range as 1-based line:col-line:col
+func formatRange(r protocol.Range) string {
+	return fmt.Sprintf("%d:%d-%d:%d", r.Start.Line+1, r.Start.Character+1, r.End.Line+1, r.End.Character+1)
+}
+
+// textEditsFromElements flattens the protocol v1.0.0 TextDocumentEditElement
+// union into plain TextEdits. gopls rename results are plain TextEdits;
+// AnnotatedTextEdit embeds one. SnippetTextEdit is not requested by goalias.
+func textEditsFromElements(elems []protocol.TextDocumentEditElement) ([]protocol.TextEdit, error) {
+	edits := make([]protocol.TextEdit, 0, len(elems))
+	for _, e := range elems {
+		switch v := e.(type) {
+		case *protocol.TextEdit:
+			edits = append(edits, *v)
+		case *protocol.AnnotatedTextEdit:
+			edits = append(edits, v.TextEdit)
+		default:
+			return nil, fmt.Errorf("unsupported text edit element type %T", e)
+		}
+	}
+	return edits, nil
+}
+
 // applyEditsToContent applies text edits to content string
 func applyEditsToContent(content string, edits []protocol.TextEdit) (string, error) {
 	if len(edits) == 0 {
@@ -1,9 +1,12 @@
 package lsp
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
 )
 
 func TestUriToFilePath(t *testing.T) {
@@ -194,3 +197,207 @@ func TestApplyEditsToContent(t *testing.T) {
 		})
 	}
 }
+
+func TestChangeSetApply(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+
+	if err := os.WriteFile(a, []byte("package a\n\nimport \"fmt\"\n"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(b, []byte("package b\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	edit := func(path string, line, start, end uint32, text string) *protocol.WorkspaceEdit {
+		return &protocol.WorkspaceEdit{
+			Changes: map[uri.URI][]protocol.TextEdit{
+				uri.File(path): {{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: line, Character: start},
+						End:   protocol.Position{Line: line, Character: end},
+					},
+					NewText: text,
+				}},
+			},
+		}
+	}
+
+	changes := NewChangeSet()
+	for _, e := range []*protocol.WorkspaceEdit{
+		edit(a, 2, 7, 7, "f "),
+		edit(a, 0, 8, 9, "alpha"),
+		// Duplicate edits are applied once
+		edit(a, 0, 8, 9, "alpha"),
+		edit(b, 0, 8, 9, "beta"),
+	} {
+		if err := changes.Add(e); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if files := changes.Files(); len(files) != 2 {
+		t.Fatalf("expected 2 files, got %v", files)
+	}
+
+	if err := changes.Apply(false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		a: "package alpha\n\nimport f \"fmt\"\n",
+		b: "package beta\n",
+	}
+	for path, content := range expected {
+		result, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		if string(result) != content {
+			t.Errorf("expected %q, got %q", content, result)
+		}
+	}
+
+	stat, err := os.Stat(a)
+	if err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+	if stat.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 to be preserved, got %v", stat.Mode().Perm())
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected no temporary files to remain, got %d entries", len(entries))
+	}
+}
+
+func TestChangeSetRejectsInvalidEdits(t *testing.T) {
+	tests := []struct {
+		name  string
+		edits []protocol.TextEdit
+	}{
+		{
+			name: "line out of range",
+			edits: []protocol.TextEdit{{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 5, Character: 0},
+					End:   protocol.Position{Line: 5, Character: 1},
+				},
+				NewText: "x",
+			}},
+		},
+		{
+			name: "character out of range",
+			edits: []protocol.TextEdit{{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: 0, Character: 3},
+					End:   protocol.Position{Line: 0, Character: 40},
+				},
+				NewText: "x",
+			}},
+		},
+		{
+			name: "overlapping edits",
+			edits: []protocol.TextEdit{
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 0, Character: 0},
+						End:   protocol.Position{Line: 0, Character: 7},
+					},
+					NewText: "x",
+				},
+				{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 0, Character: 5},
+						End:   protocol.Position{Line: 0, Character: 9},
+					},
+					NewText: "y",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "a.go")
+			original := "package a\n"
+			if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			changes := NewChangeSet()
+			err := changes.Add(&protocol.WorkspaceEdit{
+				Changes: map[uri.URI][]protocol.TextEdit{uri.File(path): tt.edits},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := changes.Apply(false); err == nil {
+				t.Errorf("expected error but got none")
+			}
+
+			result, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			if string(result) != original {
+				t.Errorf("expected file to be unchanged, got %q", result)
+			}
+		})
+	}
+}
+
+func TestWriteChangesStagingFailureLeavesFilesUntouched(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte("original"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	changes := []FileChange{
+		{Path: path, Original: []byte("original"), Modified: []byte("modified"), Mode: 0644},
+		{Path: filepath.Join(dir, "missing", "b.go"), Original: []byte("b"), Modified: []byte("c"), Mode: 0644},
+	}
+
+	if err := WriteChanges(changes); err == nil {
+		t.Fatalf("expected error but got none")
+	}
+
+	result, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(result) != "original" {
+		t.Errorf("expected file to be unchanged, got %q", result)
+	}
+}
+
+func TestRestoreChanges(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte("modified"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	err := restoreChanges([]FileChange{
+		{Path: path, Original: []byte("original"), Modified: []byte("modified"), Mode: 0644},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(result) != "original" {
+		t.Errorf("expected original contents to be restored, got %q", result)
+	}
+}
@@ -0,0 +1,329 @@
+package policy
+
+import (
+	"bufio"
+	"fmt"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jackchuka/goalias/internal/config"
+	"github.com/jackchuka/goalias/internal/discovery"
+)
+
+// Rule IDs reported with violations
+const (
+	// RuleRequiredAlias is an import whose alias differs from the one a rule
+	// or target requires
+	RuleRequiredAlias = "required-alias"
+	// RuleUnaliased is an aliased import of a path that must not be aliased
+	RuleUnaliased = "unaliased"
+	// RuleForbiddenAlias is an import using a forbidden alias
+	RuleForbiddenAlias = "forbidden-alias"
+)
+
+// Violation is an import breaking a rule of the policy
+type Violation struct {
+	discovery.ImportResult
+	// Rule is the ID of the broken rule; empty means RuleRequiredAlias
+	Rule string
+	// Required is the alias that fixes the violation, or empty when it cannot
+	// be fixed automatically
+	Required string
+	// Reason is the configured explanation of a forbidden alias
+	Reason string
+}
+
+// Line returns the 1-based line of the import spec
+func (v Violation) Line() int {
+	return v.Info.Position.Line
+}
+
+// Column returns the 1-based column of the import spec
+func (v Violation) Column() int {
+	return v.Info.Position.Column
+}
+
+// RuleID returns the ID of the broken rule
+func (v Violation) RuleID() string {
+	if v.Rule == "" {
+		return RuleRequiredAlias
+	}
+	return v.Rule
+}
+
+// Fixable reports whether renaming the import to Required fixes the violation
+func (v Violation) Fixable() bool {
+	return v.Required != ""
+}
+
+// Message describes the violation
+func (v Violation) Message() string {
+	switch v.RuleID() {
+	case RuleUnaliased:
+		return fmt.Sprintf("import %q must not be aliased, got %q", v.ImportPath, v.Info.Alias)
+	case RuleForbiddenAlias:
+		msg := fmt.Sprintf("alias %q for import %q is forbidden", v.Info.Alias, v.ImportPath)
+		if v.Reason != "" {
+			msg += ": " + v.Reason
+		}
+		return msg
+	}
+
+	return fmt.Sprintf("import %q is aliased %q, want %q", v.ImportPath, v.Alias, v.Required)
+}
+
+// Target is an import path to enforce. An empty Alias defers to the config.
+type Target struct {
+	Path  string
+	Alias string
+}
+
+// ParseTarget parses "path" or "path=alias"
+func ParseTarget(s string) (Target, error) {
+	path, alias, _ := strings.Cut(strings.TrimSpace(s), "=")
+	target := Target{Path: strings.TrimSpace(path), Alias: strings.TrimSpace(alias)}
+
+	if target.Path == "" {
+		return Target{}, fmt.Errorf("invalid package %q: import path is empty", s)
+	}
+	if target.Alias != "" && !token.IsIdentifier(target.Alias) {
+		return Target{}, fmt.Errorf("invalid alias %q for %s: not a Go identifier", target.Alias, target.Path)
+	}
+
+	return target, nil
+}
+
+// ParseMapping reads targets from a mapping file with one "path=alias" pair
+// per line. Blank lines and lines starting with # are ignored.
+func ParseMapping(r io.Reader) ([]Target, error) {
+	var targets []Target
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		target, err := ParseTarget(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if target.Alias == "" {
+			return nil, fmt.Errorf("line %d: missing alias for %s", lineNo, target.Path)
+		}
+
+		targets = append(targets, target)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mapping: %w", err)
+	}
+
+	return targets, nil
+}
+
+// Result is an import governed by a target or the config, with the alias it
+// is required to use
+type Result struct {
+	discovery.ImportResult
+	Required string
+}
+
+// Evaluate scans the files matched by patterns in a single discovery pass and
+// returns every import of a target along with its required alias. Targets
+// without an alias take it from cfg; files without a rule and blank imports
+// are skipped. With no
+// targets, every import cfg has a rule for is returned, which scans all
+// imports when cfg has pattern or regex rules.
+func Evaluate(patterns []string, opts discovery.Options, targets []Target, cfg *config.Config) ([]Result, error) {
+	results, aliases, err := scan(patterns, opts, targets, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var governed []Result
+
+	for _, result := range results {
+		if required, ok := requiredAlias(result, aliases, cfg); ok {
+			governed = append(governed, Result{ImportResult: result, Required: required})
+		}
+	}
+
+	return governed, nil
+}
+
+// Check returns every import found by Evaluate whose effective alias differs
+// from the required one. With no targets, the unaliased and forbidden rules
+// of cfg are also enforced for imports without a required alias.
+func Check(patterns []string, opts discovery.Options, targets []Target, cfg *config.Config) ([]Violation, error) {
+	results, aliases, err := scan(patterns, opts, targets, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []Violation
+
+	for _, result := range results {
+		if len(targets) == 0 {
+			if v, ok := CheckImport(result, cfg); ok {
+				violations = append(violations, v)
+			}
+			continue
+		}
+
+		// Use the effective alias (which includes inferred default aliases)
+		if required, ok := requiredAlias(result, aliases, cfg); ok && result.Alias != required {
+			violations = append(violations, Violation{ImportResult: result, Rule: RuleRequiredAlias, Required: required})
+		}
+	}
+
+	return violations, nil
+}
+
+// CheckImport applies every rule of cfg to a single import and returns the
+// violation it breaks, if any
+func CheckImport(result discovery.ImportResult, cfg *config.Config) (Violation, bool) {
+	if required, ok := requiredAlias(result, nil, cfg); ok {
+		if result.Alias == required {
+			return Violation{}, false
+		}
+		return Violation{ImportResult: result, Rule: RuleRequiredAlias, Required: required}, true
+	}
+
+	if result.Info.Alias == "" {
+		return Violation{}, false
+	}
+
+	return checkAliasRules(result, cfg)
+}
+
+// scan finds the imports Evaluate and Check consider, ordered by file and
+// line, and the aliases required by targets
+func scan(patterns []string, opts discovery.Options, targets []Target, cfg *config.Config) ([]discovery.ImportResult, map[string]string, error) {
+	aliases := make(map[string]string, len(targets))
+	importPaths := make([]string, 0, len(targets))
+	for _, t := range targets {
+		if _, ok := aliases[t.Path]; !ok {
+			importPaths = append(importPaths, t.Path)
+		}
+		aliases[t.Path] = t.Alias
+	}
+
+	var (
+		results []discovery.ImportResult
+		err     error
+	)
+	switch {
+	case len(targets) > 0:
+		results, err = discovery.FindImports(patterns, importPaths, opts)
+	case cfg.HasPatterns() || (cfg != nil && len(cfg.Forbidden) > 0):
+		results, err = discovery.FindAllImports(patterns, opts)
+	default:
+		paths := cfg.ImportPaths()
+		if cfg != nil {
+			paths = append(paths, cfg.Unaliased...)
+		}
+		results, err = discovery.FindImports(patterns, paths, opts)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].File != results[j].File {
+			return results[i].File < results[j].File
+		}
+		return results[i].Info.Position.Line < results[j].Info.Position.Line
+	})
+
+	return results, aliases, nil
+}
+
+// requiredAlias returns the alias result must use, from its target or cfg.
+// Blank imports only exist for their side effects and never require one.
+func requiredAlias(result discovery.ImportResult, aliases map[string]string, cfg *config.Config) (string, bool) {
+	if result.Info.Alias == "_" {
+		return "", false
+	}
+
+	if required := aliases[result.ImportPath]; required != "" {
+		return required, true
+	}
+	return cfg.AliasFor(result.File, result.ImportPath)
+}
+
+// checkAliasRules applies the unaliased and forbidden rules of cfg to an
+// explicitly aliased import. Where possible the fix is the package name.
+func checkAliasRules(result discovery.ImportResult, cfg *config.Config) (Violation, bool) {
+	alias := result.Info.Alias
+	name := discovery.DefaultAlias(result.ImportPath)
+
+	// Naming a blank import would leave it unused; dot imports are fixed by
+	// conversion, which set only does when asked to
+	fixable := alias != "_"
+
+	if cfg.MustBeUnaliased(result.ImportPath) {
+		v := Violation{ImportResult: result, Rule: RuleUnaliased}
+		if fixable {
+			v.Required = name
+		}
+		return v, true
+	}
+
+	forbid, ok := cfg.ForbiddenAlias(alias, result.Info.FilePackage)
+	if !ok {
+		return Violation{}, false
+	}
+
+	v := Violation{ImportResult: result, Rule: RuleForbiddenAlias, Reason: forbid.Message}
+	if _, banned := cfg.ForbiddenAlias(name, result.Info.FilePackage); fixable && !banned && token.IsIdentifier(name) {
+		v.Required = name
+	}
+	return v, true
+}
+
+// WriteText writes violations in a compiler-like "file:line:col: message (rule)"
+// format. relPath rewrites file names for display and may be nil.
+func WriteText(w io.Writer, violations []Violation, relPath func(string) string) error {
+	for _, v := range violations {
+		if _, err := fmt.Fprintf(w, "%s:%d:%d: %s (%s)\n", displayPath(v.File, relPath), v.Line(), v.Column(), v.Message(), v.RuleID()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteGitHub writes violations as GitHub Actions error annotations
+func WriteGitHub(w io.Writer, violations []Violation, relPath func(string) string) error {
+	for _, v := range violations {
+		_, err := fmt.Fprintf(w, "::error file=%s,line=%d,col=%d,title=%s::%s\n",
+			escapeProperty(displayPath(v.File, relPath)), v.Line(), v.Column(), escapeProperty("goalias "+v.RuleID()), escapeData(v.Message()))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func displayPath(file string, relPath func(string) string) string {
+	if relPath == nil {
+		return file
+	}
+	return relPath(file)
+}
+
+// escapeData escapes a GitHub workflow command message
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeProperty escapes a GitHub workflow command property value
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
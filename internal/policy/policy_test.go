@@ -0,0 +1,402 @@
+package policy
+
+import (
+	"bytes"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackchuka/goalias/internal/config"
+	"github.com/jackchuka/goalias/internal/discovery"
+	"github.com/jackchuka/goalias/internal/discovery/ast"
+)
+
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/app\n\ngo 1.22\n"
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	return dir
+}
+
+func TestCheck(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"util/util.go": "package util\n\nfunc Do() {}\n",
+		"a/a.go":       "package a\n\nimport u \"example.com/app/util\"\n\nfunc A() { u.Do() }\n",
+		"b/b.go":       "package b\n\nimport \"example.com/app/util\"\n\nfunc B() { util.Do() }\n",
+		"c/c.go":       "package c\n\nimport myutil \"example.com/app/util\"\n\nfunc C() { myutil.Do() }\n",
+	})
+	t.Chdir(dir)
+
+	cfg := &config.Config{
+		Root:  dir,
+		Rules: []config.Rule{{Path: "example.com/app/util", Alias: "myutil"}},
+	}
+
+	violations, err := Check([]string{"./..."}, discovery.Options{}, []Target{{Path: "example.com/app/util"}}, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(violations) != 2 {
+		t.Fatalf("expected 2 violations, got %d", len(violations))
+	}
+
+	expected := []struct {
+		file  string
+		alias string
+	}{
+		{file: "a/a.go", alias: "u"},
+		{file: "b/b.go", alias: "util"},
+	}
+
+	for i, e := range expected {
+		v := violations[i]
+		if filepath.Base(v.File) != filepath.Base(e.file) {
+			t.Errorf("violation %d: expected file %q, got %q", i, e.file, v.File)
+		}
+		if v.Alias != e.alias {
+			t.Errorf("violation %d: expected alias %q, got %q", i, e.alias, v.Alias)
+		}
+		if v.Required != "myutil" {
+			t.Errorf("violation %d: expected required alias %q, got %q", i, "myutil", v.Required)
+		}
+	}
+
+	// An explicit alias overrides the config
+	violations, err = Check([]string{"./..."}, discovery.Options{}, []Target{{Path: "example.com/app/util", Alias: "u"}}, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 2 {
+		t.Errorf("expected 2 violations with explicit alias, got %d", len(violations))
+	}
+}
+
+func TestCheckMultipleTargets(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"util/util.go": "package util\n\nfunc Do() {}\n",
+		"a/a.go":       "package a\n\nimport (\n\t\"fmt\"\n\tu \"example.com/app/util\"\n)\n\nfunc A() { u.Do(); fmt.Println() }\n",
+	})
+	t.Chdir(dir)
+
+	targets := []Target{
+		{Path: "example.com/app/util", Alias: "myutil"},
+		{Path: "fmt", Alias: "stdfmt"},
+	}
+
+	violations, err := Check([]string{"./..."}, discovery.Options{}, targets, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(violations) != 2 {
+		t.Fatalf("expected 2 violations, got %d", len(violations))
+	}
+
+	if violations[0].ImportPath != "fmt" || violations[0].Required != "stdfmt" {
+		t.Errorf("unexpected first violation %+v", violations[0])
+	}
+	if violations[1].ImportPath != "example.com/app/util" || violations[1].Required != "myutil" {
+		t.Errorf("unexpected second violation %+v", violations[1])
+	}
+}
+
+func TestCheckSkipsBlankImports(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"util/util.go": "package util\n\nfunc init() {}\n",
+		"a/a.go":       "package a\n\nimport _ \"example.com/app/util\"\n",
+		"b/b.go":       "package b\n\nimport . \"example.com/app/util\"\n",
+	})
+	t.Chdir(dir)
+
+	violations, err := Check([]string{"./..."}, discovery.Options{}, []Target{{Path: "example.com/app/util", Alias: "u"}}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(violations) != 1 {
+		t.Fatalf("expected only the dot import to be reported, got %+v", violations)
+	}
+	if violations[0].Info.Alias != "." || violations[0].Required != "u" {
+		t.Errorf("unexpected violation %+v", violations[0])
+	}
+}
+
+func TestEvaluatePolicy(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"api/core/v1/types.go": "package v1\n\nfunc Do() {}\n",
+		"api/apps/v1/types.go": "package v1\n\nfunc Do() {}\n",
+		"util/util.go":         "package util\n\nfunc Do() {}\n",
+		"a/a.go":               "package a\n\nimport (\n\tcorev1 \"example.com/app/api/core/v1\"\n\t\"example.com/app/api/apps/v1\"\n\t\"example.com/app/util\"\n)\n\nfunc A() { corev1.Do(); v1.Do(); util.Do() }\n",
+	})
+	t.Chdir(dir)
+
+	cfg, err := config.Parse([]byte("rules:\n  - pattern: example.com/app/api/*/v1\n    alias: ${1}v1\n"))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	cfg.Root = dir
+
+	results, err := Evaluate([]string{"./..."}, discovery.Options{}, nil, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 governed imports, got %d", len(results))
+	}
+	if results[0].ImportPath != "example.com/app/api/core/v1" || results[0].Required != "corev1" {
+		t.Errorf("unexpected first result %+v", results[0])
+	}
+	if results[1].ImportPath != "example.com/app/api/apps/v1" || results[1].Required != "appsv1" {
+		t.Errorf("unexpected second result %+v", results[1])
+	}
+
+	violations, err := Check([]string{"./..."}, discovery.Options{}, nil, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Alias != "v1" || violations[0].Required != "appsv1" {
+		t.Errorf("expected one violation for the apps import, got %+v", violations)
+	}
+}
+
+func TestCheckAliasRules(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"util/util.go": "package util\n\nfunc Do() {}\n",
+		"a/a.go":       "package a\n\nimport (\n\tctx \"context\"\n\tu \"example.com/app/util\"\n\t_ \"embed\"\n\tmyutil \"example.com/app/util\"\n)\n\nvar _ ctx.Context\n\nfunc A() { u.Do(); myutil.Do() }\n",
+		"main.go":      "package main\n\nimport _ \"embed\"\n\nfunc main() {}\n",
+		"b/b.go":       "package b\n\nimport \"context\"\n\nvar _ context.Context\n",
+	})
+	t.Chdir(dir)
+
+	cfg, err := config.Parse([]byte(`unaliased:
+  - context
+forbidden:
+  - alias: _
+    except: [main]
+    message: blank imports belong in package main
+  - regex: '[a-z]'
+    message: single-letter aliases are not allowed
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	cfg.Root = dir
+
+	violations, err := Check([]string{"./..."}, discovery.Options{}, nil, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		line     int
+		rule     string
+		required string
+		message  string
+	}{
+		{
+			line:     4,
+			rule:     RuleUnaliased,
+			required: "context",
+			message:  `import "context" must not be aliased, got "ctx"`,
+		},
+		{
+			line:     5,
+			rule:     RuleForbiddenAlias,
+			required: "util",
+			message:  `alias "u" for import "example.com/app/util" is forbidden: single-letter aliases are not allowed`,
+		},
+		{
+			line:    6,
+			rule:    RuleForbiddenAlias,
+			message: `alias "_" for import "embed" is forbidden: blank imports belong in package main`,
+		},
+	}
+
+	if len(violations) != len(expected) {
+		t.Fatalf("expected %d violations, got %d: %+v", len(expected), len(violations), violations)
+	}
+
+	for i, e := range expected {
+		v := violations[i]
+		if v.Line() != e.line || v.RuleID() != e.rule || v.Required != e.required || v.Message() != e.message {
+			t.Errorf("violation %d: expected line %d %s %q %q, got line %d %s %q %q",
+				i, e.line, e.rule, e.required, e.message, v.Line(), v.RuleID(), v.Required, v.Message())
+		}
+		if v.Fixable() != (e.required != "") {
+			t.Errorf("violation %d: unexpected Fixable %v", i, v.Fixable())
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  Target
+		expectErr bool
+	}{
+		{
+			name:     "path only",
+			input:    "github.com/pkg/errors",
+			expected: Target{Path: "github.com/pkg/errors"},
+		},
+		{
+			name:     "path and alias",
+			input:    "github.com/pkg/errors=pkgerrors",
+			expected: Target{Path: "github.com/pkg/errors", Alias: "pkgerrors"},
+		},
+		{
+			name:     "surrounding whitespace",
+			input:    " github.com/pkg/errors = pkgerrors ",
+			expected: Target{Path: "github.com/pkg/errors", Alias: "pkgerrors"},
+		},
+		{
+			name:      "empty path",
+			input:     "=pkgerrors",
+			expectErr: true,
+		},
+		{
+			name:      "invalid alias",
+			input:     "github.com/pkg/errors=pkg-errors",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseTarget(tt.input)
+
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			if result != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	input := `# migration aliases
+github.com/pkg/errors=pkgerrors
+
+example.com/app/util = myutil
+`
+
+	targets, err := ParseMapping(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Target{
+		{Path: "github.com/pkg/errors", Alias: "pkgerrors"},
+		{Path: "example.com/app/util", Alias: "myutil"},
+	}
+
+	if len(targets) != len(expected) {
+		t.Fatalf("expected %d targets, got %d", len(expected), len(targets))
+	}
+
+	for i, e := range expected {
+		if targets[i] != e {
+			t.Errorf("target %d: expected %+v, got %+v", i, e, targets[i])
+		}
+	}
+
+	if _, err := ParseMapping(strings.NewReader("github.com/pkg/errors\n")); err == nil {
+		t.Errorf("expected error for mapping without alias")
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	violations := []Violation{
+		{
+			ImportResult: discovery.ImportResult{
+				File:       "/repo/a/a.go",
+				ImportPath: "example.com/app/util",
+				Alias:      "u",
+				Info: &ast.ImportInfo{
+					Position: token.Position{Line: 3, Column: 8},
+					Found:    true,
+				},
+			},
+			Required: "myutil",
+		},
+	}
+
+	rel := func(file string) string { return "a/a.go" }
+
+	tests := []struct {
+		name     string
+		write    func(*bytes.Buffer) error
+		expected string
+	}{
+		{
+			name: "text",
+			write: func(b *bytes.Buffer) error {
+				return WriteText(b, violations, rel)
+			},
+			expected: "a/a.go:3:8: import \"example.com/app/util\" is aliased \"u\", want \"myutil\" (required-alias)\n",
+		},
+		{
+			name: "text without relPath",
+			write: func(b *bytes.Buffer) error {
+				return WriteText(b, violations, nil)
+			},
+			expected: "/repo/a/a.go:3:8: import \"example.com/app/util\" is aliased \"u\", want \"myutil\" (required-alias)\n",
+		},
+		{
+			name: "github",
+			write: func(b *bytes.Buffer) error {
+				return WriteGitHub(b, violations, rel)
+			},
+			expected: "::error file=a/a.go,line=3,col=8,title=goalias required-alias::import \"example.com/app/util\" is aliased \"u\", want \"myutil\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.write(&buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestEscapeProperty(t *testing.T) {
+	if result := escapeProperty("C:\\a,b%"); result != "C%3A\\a%2Cb%25" {
+		t.Errorf("unexpected escape result %q", result)
+	}
+	if result := escapeData("a\nb"); result != "a%0Ab" {
+		t.Errorf("unexpected escape result %q", result)
+	}
+}
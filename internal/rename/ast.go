@@ -0,0 +1,215 @@
+package rename
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jackchuka/goalias/internal/discovery"
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// ASTEngine renames imports using go/ast and go/types without a language
+// server. Each file is type-checked on its own against stub packages, which
+// is enough to resolve every reference to the import through scoping.
+type ASTEngine struct {
+	// PackageName returns the declared name of an imported package. Nil
+	// uses discovery.DefaultAlias.
+	PackageName func(importPath string) string
+}
+
+// NewASTEngine creates an ASTEngine
+func NewASTEngine() *ASTEngine {
+	return &ASTEngine{}
+}
+
+// Rename renames the import spec at the given position and every reference
+// to it in the file. Renaming to the package's own name drops the explicit
+// name, matching gopls.
+func (e *ASTEngine) Rename(filePath string, line, character int, newName string) (*protocol.WorkspaceEdit, error) {
+	if !token.IsIdentifier(newName) {
+		return nil, fmt.Errorf("invalid alias %q: not a Go identifier", newName)
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, absPath, nil, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+
+	spec := findImportSpec(fset, file, line+1, character+1)
+	if spec == nil {
+		return nil, fmt.Errorf("no import spec at %s:%d:%d", filePath, line+1, character+1)
+	}
+
+	if spec.Name != nil && (spec.Name.Name == "_" || spec.Name.Name == ".") {
+		return nil, fmt.Errorf("cannot rename %s import of %s", spec.Name.Name, spec.Path.Value)
+	}
+
+	info := e.check(fset, file)
+
+	obj := importedName(info, spec)
+	if obj == nil {
+		return nil, fmt.Errorf("failed to resolve import %s", spec.Path.Value)
+	}
+
+	var edits []protocol.TextEdit
+
+	switch {
+	case newName == obj.Imported().Name() && spec.Name != nil:
+		// Drop the explicit name along with the space before the path
+		edits = append(edits, textEdit(fset, spec.Name.Pos(), spec.Path.Pos(), ""))
+	case spec.Name != nil:
+		edits = append(edits, textEdit(fset, spec.Name.Pos(), spec.Name.End(), newName))
+	case newName != obj.Imported().Name():
+		edits = append(edits, textEdit(fset, spec.Path.Pos(), spec.Path.Pos(), newName+" "))
+	}
+
+	for _, ident := range references(info, obj) {
+		edits = append(edits, textEdit(fset, ident.Pos(), ident.End(), newName))
+	}
+
+	return &protocol.WorkspaceEdit{
+		Changes: map[uri.URI][]protocol.TextEdit{
+			uri.File(absPath): edits,
+		},
+	}, nil
+}
+
+// check type-checks file on its own. Imports resolve to empty stub packages,
+// so errors about their members or other files' declarations are expected
+// and ignored; scoping is still fully resolved.
+func (e *ASTEngine) check(fset *token.FileSet, file *ast.File) *types.Info {
+	info := &types.Info{
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+		Scopes:    make(map[ast.Node]*types.Scope),
+	}
+
+	if e.PackageName == nil {
+		resolvePackageNames(file)
+	}
+
+	conf := types.Config{
+		Importer:    stubImporter{packageName: e.packageName},
+		FakeImportC: true,
+		Error:       func(error) {},
+	}
+
+	// Errors are reported through conf.Error; the returned error only
+	// repeats the first one
+	_, _ = conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
+
+	return info
+}
+
+func (e *ASTEngine) packageName(importPath string) string {
+	if e.PackageName != nil {
+		return e.PackageName(importPath)
+	}
+	return discovery.DefaultAlias(importPath)
+}
+
+// resolvePackageNames looks up the package names of every import of files
+// with a single go list, rather than one per import as the type checker asks
+// for them. Paths that fail to resolve fall back to discovery.DefaultAlias's
+// heuristic.
+func resolvePackageNames(files ...*ast.File) {
+	var paths []string
+	for _, file := range files {
+		for _, spec := range file.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+	_ = discovery.ResolvePackageNames(paths)
+}
+
+// stubImporter returns empty, complete packages named after the real package
+type stubImporter struct {
+	packageName func(string) string
+}
+
+func (s stubImporter) Import(path string) (*types.Package, error) {
+	pkg := types.NewPackage(path, s.packageName(path))
+	pkg.MarkComplete()
+	return pkg, nil
+}
+
+// findImportSpec returns the import spec starting at, or containing, the
+// 1-based line and column
+func findImportSpec(fset *token.FileSet, file *ast.File, line, column int) *ast.ImportSpec {
+	for _, spec := range file.Imports {
+		start := fset.Position(spec.Pos())
+		end := fset.Position(spec.End())
+
+		if line < start.Line || line > end.Line {
+			continue
+		}
+		if line == start.Line && column < start.Column {
+			continue
+		}
+		if line == end.Line && column > end.Column {
+			continue
+		}
+
+		return spec
+	}
+	return nil
+}
+
+// importedName returns the object the import spec declares in the file scope
+func importedName(info *types.Info, spec *ast.ImportSpec) *types.PkgName {
+	var obj types.Object
+	if spec.Name != nil {
+		obj = info.Defs[spec.Name]
+	} else {
+		obj = info.Implicits[spec]
+	}
+
+	pkgName, _ := obj.(*types.PkgName)
+	return pkgName
+}
+
+// references returns every identifier referring to obj
+func references(info *types.Info, obj types.Object) []*ast.Ident {
+	var idents []*ast.Ident
+	for ident, used := range info.Uses {
+		if used == obj {
+			idents = append(idents, ident)
+		}
+	}
+	return idents
+}
+
+// textEdit replaces the source between start and end with newText
+func textEdit(fset *token.FileSet, start, end token.Pos, newText string) protocol.TextEdit {
+	return protocol.TextEdit{
+		Range: protocol.Range{
+			Start: lspPosition(fset.Position(start)),
+			End:   lspPosition(fset.Position(end)),
+		},
+		NewText: newText,
+	}
+}
+
+// lspPosition converts a token position to a 0-based protocol position. Like
+// the workspace edit applier, characters are counted in bytes.
+func lspPosition(pos token.Position) protocol.Position {
+	return protocol.Position{
+		Line:      uint32(pos.Line - 1),
+		Character: uint32(pos.Column - 1),
+	}
+}
@@ -0,0 +1,201 @@
+package rename
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackchuka/goalias/internal/lsp"
+)
+
+func TestASTEngineRename(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		line      int
+		character int
+		newName   string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name: "add alias to unaliased import",
+			content: `package main
+
+import "strings"
+
+func main() {
+	_ = strings.ToUpper("a")
+}
+`,
+			line:      2,
+			character: 7,
+			newName:   "str",
+			expected: `package main
+
+import str "strings"
+
+func main() {
+	_ = str.ToUpper("a")
+}
+`,
+		},
+		{
+			name: "replace existing alias",
+			content: `package main
+
+import (
+	"fmt"
+	s "strings"
+)
+
+func main() {
+	fmt.Println(s.ToUpper("a"), s.ToLower("B"))
+}
+`,
+			line:      4,
+			character: 1,
+			newName:   "str",
+			expected: `package main
+
+import (
+	"fmt"
+	str "strings"
+)
+
+func main() {
+	fmt.Println(str.ToUpper("a"), str.ToLower("B"))
+}
+`,
+		},
+		{
+			name: "shadowed identifiers are left alone",
+			content: `package main
+
+import s "strings"
+
+func f(s string) string { return s }
+
+func main() {
+	_ = s.ToUpper(f("a"))
+	{
+		s := "x"
+		_ = s
+	}
+}
+`,
+			line:      2,
+			character: 7,
+			newName:   "str",
+			expected: `package main
+
+import str "strings"
+
+func f(s string) string { return s }
+
+func main() {
+	_ = str.ToUpper(f("a"))
+	{
+		s := "x"
+		_ = s
+	}
+}
+`,
+		},
+		{
+			name: "renaming to package name drops alias",
+			content: `package main
+
+import s "strings"
+
+func main() {
+	_ = s.ToUpper("a")
+}
+`,
+			line:      2,
+			character: 7,
+			newName:   "strings",
+			expected: `package main
+
+import "strings"
+
+func main() {
+	_ = strings.ToUpper("a")
+}
+`,
+		},
+		{
+			name: "no import at position",
+			content: `package main
+
+import "strings"
+
+var _ = strings.ToUpper
+`,
+			line:      4,
+			character: 0,
+			newName:   "str",
+			expectErr: true,
+		},
+		{
+			name: "invalid alias",
+			content: `package main
+
+import "strings"
+
+var _ = strings.ToUpper
+`,
+			line:      2,
+			character: 7,
+			newName:   "not-valid",
+			expectErr: true,
+		},
+		{
+			name: "blank import cannot be renamed",
+			content: `package main
+
+import _ "strings"
+`,
+			line:      2,
+			character: 7,
+			newName:   "str",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "main.go")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			engine := NewASTEngine()
+			edit, err := engine.Rename(path, tt.line, tt.character, tt.newName)
+
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := lsp.ApplyWorkspaceEdit(edit, false); err != nil {
+				t.Fatalf("failed to apply edit: %v", err)
+			}
+
+			result, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+
+			if string(result) != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
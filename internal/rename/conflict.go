@@ -0,0 +1,222 @@
+package rename
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jackchuka/goalias/internal/discovery"
+)
+
+// Conflict is a declaration or reference that makes renaming an import to a
+// new alias fail to compile or change meaning
+type Conflict struct {
+	Position token.Position
+	Message  string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: %s", c.Position, c.Message)
+}
+
+// FindConflicts type-checks the package containing filePath and reports
+// everything that collides with renaming the import spec at the 0-based
+// line/character position to newName: other imports, package-level
+// declarations, local declarations that would shadow a reference to the
+// import, and predeclared identifiers the new name would hide.
+func FindConflicts(filePath string, line, character int, newName string) ([]Conflict, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, absPath, nil, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+
+	spec := findImportSpec(fset, file, line+1, character+1)
+	if spec == nil {
+		return nil, fmt.Errorf("no import spec at %s:%d:%d", filePath, line+1, character+1)
+	}
+
+	files := append([]*ast.File{file}, parsePackageSiblings(fset, absPath, file.Name.Name)...)
+
+	info := &types.Info{
+		Defs:      make(map[*ast.Ident]types.Object),
+		Uses:      make(map[*ast.Ident]types.Object),
+		Implicits: make(map[ast.Node]types.Object),
+		Scopes:    make(map[ast.Node]*types.Scope),
+	}
+
+	resolvePackageNames(files...)
+
+	conf := types.Config{
+		Importer:    stubImporter{packageName: discovery.DefaultAlias},
+		FakeImportC: true,
+		Error:       func(error) {},
+	}
+	pkg, _ := conf.Check(file.Name.Name, fset, files, info)
+
+	obj := importedName(info, spec)
+	if obj == nil {
+		return nil, fmt.Errorf("failed to resolve import %s", spec.Path.Value)
+	}
+	if obj.Name() == newName {
+		return nil, nil
+	}
+
+	var conflicts []Conflict
+	fileScope := info.Scopes[file]
+
+	// Another import in the file already uses the name. A duplicate import
+	// of the same path is merged rather than a conflict.
+	if other := fileScope.Lookup(newName); other != nil && !importsSamePath(other, obj) {
+		conflicts = append(conflicts, Conflict{
+			Position: fset.Position(other.Pos()),
+			Message:  fmt.Sprintf("alias %q is already used by %s", newName, describe(other)),
+		})
+	}
+
+	// A package-level declaration in any file of the package
+	if other := pkg.Scope().Lookup(newName); other != nil {
+		conflicts = append(conflicts, Conflict{
+			Position: fset.Position(other.Pos()),
+			Message:  fmt.Sprintf("alias %q conflicts with package-level %s", newName, describe(other)),
+		})
+	}
+
+	// A local declaration that would capture a reference to the import
+	shadowing := make(map[types.Object]bool)
+	for _, ident := range references(info, obj) {
+		scope := pkg.Scope().Innermost(ident.Pos())
+		if scope == nil {
+			continue
+		}
+
+		_, other := scope.LookupParent(newName, ident.Pos())
+		if other == nil || shadowing[other] || other.Parent() == fileScope || other.Parent() == pkg.Scope() || other.Parent() == types.Universe {
+			continue
+		}
+		shadowing[other] = true
+
+		conflicts = append(conflicts, Conflict{
+			Position: fset.Position(other.Pos()),
+			Message: fmt.Sprintf("alias %q would be shadowed by local %s, which is in scope at line %d",
+				newName, describe(other), fset.Position(ident.Pos()).Line),
+		})
+	}
+
+	// A predeclared identifier used in the file that the alias would hide;
+	// the first use is reported
+	var hidden *ast.Ident
+	for ident, used := range info.Uses {
+		if used.Parent() != types.Universe || used.Name() != newName || fset.File(ident.Pos()) != fset.File(file.Pos()) {
+			continue
+		}
+		if hidden == nil || ident.Pos() < hidden.Pos() {
+			hidden = ident
+		}
+	}
+	if hidden != nil {
+		conflicts = append(conflicts, Conflict{
+			Position: fset.Position(hidden.Pos()),
+			Message:  fmt.Sprintf("alias %q would hide predeclared %s used here", newName, describe(info.Uses[hidden])),
+		})
+	}
+
+	return conflicts, nil
+}
+
+// parsePackageSiblings parses the other files in the directory of filePath
+// that declare the same package. Build constraints are ignored, which errs
+// on the side of reporting a conflict.
+func parsePackageSiblings(fset *token.FileSet, filePath, packageName string) []*ast.File {
+	entries, err := os.ReadDir(filepath.Dir(filePath))
+	if err != nil {
+		return nil
+	}
+
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(filepath.Dir(filePath), name)
+
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || path == filePath {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+		if err != nil || f.Name.Name != packageName {
+			continue
+		}
+
+		files = append(files, f)
+	}
+
+	return files
+}
+
+// importsSamePath reports whether other is an import of the same package as obj
+func importsSamePath(other types.Object, obj *types.PkgName) bool {
+	pkgName, ok := other.(*types.PkgName)
+	return ok && pkgName.Imported().Path() == obj.Imported().Path()
+}
+
+// describe names the kind and identifier of obj for messages
+func describe(obj types.Object) string {
+	kind := "identifier"
+	switch o := obj.(type) {
+	case *types.PkgName:
+		return fmt.Sprintf("import of %q", o.Imported().Path())
+	case *types.Var:
+		if o.IsField() {
+			kind = "field"
+		} else {
+			kind = "variable"
+		}
+	case *types.Func:
+		kind = "function"
+	case *types.Const:
+		kind = "constant"
+	case *types.TypeName:
+		kind = "type"
+	case *types.Label:
+		kind = "label"
+	case *types.Builtin:
+		kind = "builtin"
+	case *types.Nil:
+		kind = "value"
+	}
+	return fmt.Sprintf("%s %s", kind, obj.Name())
+}
+
+// FallbackAliases expands pattern into candidate aliases for a file where
+// alias conflicts. {alias} is replaced with the conflicting alias and {n}
+// with 2 through 9; a pattern without {n} yields a single candidate.
+func FallbackAliases(pattern, alias string) []string {
+	pattern = strings.ReplaceAll(pattern, "{alias}", alias)
+
+	if !strings.Contains(pattern, "{n}") {
+		if token.IsIdentifier(pattern) {
+			return []string{pattern}
+		}
+		return nil
+	}
+
+	var candidates []string
+	for n := 2; n <= 9; n++ {
+		candidate := strings.ReplaceAll(pattern, "{n}", fmt.Sprint(n))
+		if token.IsIdentifier(candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	return candidates
+}
@@ -0,0 +1,211 @@
+package rename
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		siblings map[string]string
+		newName  string
+		expected []string
+	}{
+		{
+			name: "no conflict",
+			content: `package main
+
+import "strings"
+
+func main() {
+	_ = strings.ToUpper("a")
+}
+`,
+			newName: "str",
+		},
+		{
+			name: "another import",
+			content: `package main
+
+import (
+	"strings"
+	str "strconv"
+)
+
+func main() {
+	_ = strings.ToUpper(str.Itoa(1))
+}
+`,
+			newName:  "str",
+			expected: []string{`already used by import of "strconv"`},
+		},
+		{
+			name: "package-level declaration in sibling file",
+			content: `package main
+
+import "strings"
+
+func main() {
+	_ = strings.ToUpper("a")
+}
+`,
+			siblings: map[string]string{
+				"other.go": "package main\n\nfunc str() {}\n",
+			},
+			newName:  "str",
+			expected: []string{"package-level function str"},
+		},
+		{
+			name: "local variable shadows a reference",
+			content: `package main
+
+import "strings"
+
+func main() {
+	str := "a"
+	_ = strings.ToUpper(str)
+}
+`,
+			newName:  "str",
+			expected: []string{"shadowed by local variable str"},
+		},
+		{
+			name: "parameter shadows a reference",
+			content: `package main
+
+import "strings"
+
+func f(str string) string {
+	return strings.ToUpper(str)
+}
+`,
+			newName:  "str",
+			expected: []string{"shadowed by local variable str"},
+		},
+		{
+			name: "local declaration not in scope of a reference",
+			content: `package main
+
+import "strings"
+
+func f() {
+	str := "a"
+	_ = str
+}
+
+func g() string {
+	return strings.ToUpper("b")
+}
+`,
+			newName: "str",
+		},
+		{
+			name: "hides a predeclared identifier",
+			content: `package main
+
+import "strings"
+
+func main() {
+	_ = strings.ToUpper(string(rune(len("a"))))
+}
+`,
+			newName:  "len",
+			expected: []string{"hide predeclared builtin len"},
+		},
+		{
+			name: "sibling in another package is ignored",
+			content: `package main
+
+import "strings"
+
+func main() {
+	_ = strings.ToUpper("a")
+}
+`,
+			siblings: map[string]string{
+				"other_test.go": "package main_test\n\nfunc str() {}\n",
+			},
+			newName: "str",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "main.go")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+			for name, content := range tt.siblings {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			// The strings import is always on line 3, column 8 or in a group
+			line, character := 2, 7
+			if strings.Contains(tt.content, "import (") {
+				line, character = 3, 1
+			}
+
+			conflicts, err := FindConflicts(path, line, character, tt.newName)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(conflicts) != len(tt.expected) {
+				t.Fatalf("expected %d conflicts, got %d: %v", len(tt.expected), len(conflicts), conflicts)
+			}
+
+			for i, e := range tt.expected {
+				if !strings.Contains(conflicts[i].Message, e) {
+					t.Errorf("conflict %d: expected message containing %q, got %q", i, e, conflicts[i].Message)
+				}
+				if conflicts[i].Position.Line == 0 {
+					t.Errorf("conflict %d: expected a position", i)
+				}
+			}
+		})
+	}
+}
+
+func TestFallbackAliases(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		alias    string
+		expected []string
+	}{
+		{
+			name:     "numbered",
+			pattern:  "{alias}{n}",
+			alias:    "str",
+			expected: []string{"str2", "str3", "str4", "str5", "str6", "str7", "str8", "str9"},
+		},
+		{
+			name:     "fixed",
+			pattern:  "{alias}pkg",
+			alias:    "str",
+			expected: []string{"strpkg"},
+		},
+		{
+			name:     "invalid identifier",
+			pattern:  "{alias}-x",
+			alias:    "str",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := FallbackAliases(tt.pattern, tt.alias)
+			if strings.Join(result, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
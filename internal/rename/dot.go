@@ -0,0 +1,119 @@
+package rename
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+	"golang.org/x/tools/go/packages"
+)
+
+// ConvertDotImport replaces the dot import at the 0-based line/character
+// position with an import named newName and qualifies every reference to a
+// member of the imported package with it. Unlike renames, this needs the
+// imported package's members, so the containing package is fully loaded and
+// type-checked. Converting to the package's own name leaves the import
+// unnamed.
+func ConvertDotImport(filePath string, line, character int, newName string) (*protocol.WorkspaceEdit, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	pkg, file, err := loadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	fset := pkg.Fset
+
+	spec := findImportSpec(fset, file, line+1, character+1)
+	if spec == nil {
+		return nil, fmt.Errorf("no import spec at %s:%d:%d", filePath, line+1, character+1)
+	}
+	if spec.Name == nil || spec.Name.Name != "." {
+		return nil, fmt.Errorf("import %s is not a dot import", spec.Path.Value)
+	}
+
+	pkgName := pkg.TypesInfo.PkgNameOf(spec)
+	if pkgName == nil {
+		return nil, fmt.Errorf("failed to resolve import %s", spec.Path.Value)
+	}
+	imported := pkgName.Imported()
+
+	var edits []protocol.TextEdit
+	if newName == imported.Name() {
+		// Drop the dot along with the space before the path
+		edits = append(edits, textEdit(fset, spec.Name.Pos(), spec.Path.Pos(), ""))
+	} else {
+		edits = append(edits, textEdit(fset, spec.Name.Pos(), spec.Name.End(), newName))
+	}
+
+	// Selector fields are already qualified by something else
+	selected := make(map[*ast.Ident]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			selected[sel.Sel] = true
+		}
+		return true
+	})
+
+	for ident, obj := range pkg.TypesInfo.Uses {
+		if selected[ident] || ident.Pos() < file.Pos() || ident.Pos() > file.End() {
+			continue
+		}
+
+		// Only package-level members are reached through the dot import;
+		// fields and methods are selected from values
+		if obj.Pkg() != imported || imported.Scope().Lookup(obj.Name()) != obj {
+			continue
+		}
+
+		if scope := pkg.Types.Scope().Innermost(ident.Pos()); scope != nil {
+			if _, other := scope.LookupParent(newName, ident.Pos()); other != nil && other.Parent() != types.Universe {
+				return nil, fmt.Errorf("alias %q would be shadowed by %s at line %d",
+					newName, describe(other), fset.Position(ident.Pos()).Line)
+			}
+		}
+
+		edits = append(edits, textEdit(fset, ident.Pos(), ident.Pos(), newName+"."))
+	}
+
+	return &protocol.WorkspaceEdit{
+		Changes: map[uri.URI][]protocol.TextEdit{
+			uri.File(absPath): edits,
+		},
+	}, nil
+}
+
+// loadFile loads and type-checks the package containing absPath, including
+// test variants for test files, and returns the file's syntax tree
+func loadFile(absPath string) (*packages.Package, *ast.File, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+			packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:   filepath.Dir(absPath),
+		Tests: strings.HasSuffix(absPath, "_test.go"),
+	}
+
+	pkgs, err := packages.Load(cfg, "file="+absPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load package: %w", err)
+	}
+
+	for _, pkg := range pkgs {
+		if pkg.TypesInfo == nil {
+			continue
+		}
+		for _, file := range pkg.Syntax {
+			if pkg.Fset.Position(file.Pos()).Filename == absPath {
+				return pkg, file, nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("no package loaded for %s; it may be excluded by build constraints", absPath)
+}
@@ -0,0 +1,169 @@
+package rename
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackchuka/goalias/internal/lsp"
+)
+
+func TestConvertDotImport(t *testing.T) {
+	shapes := `package shapes
+
+type Point struct{ X, Y int }
+
+const Origin = 0
+
+func New(x, y int) Point { return Point{X: x, Y: y} }
+
+func (p Point) Add(q Point) Point { return New(p.X+q.X, p.Y+q.Y) }
+`
+
+	tests := []struct {
+		name      string
+		content   string
+		line      int
+		character int
+		newName   string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name: "qualify package members",
+			content: `package main
+
+import (
+	"fmt"
+
+	. "example.com/app/shapes"
+)
+
+func main() {
+	p := New(Origin, 1)
+	var q Point = Point{X: 2}
+	m := map[int]Point{Origin: p}
+	fmt.Println(p.Add(q).X, m)
+}
+`,
+			line:      5,
+			character: 1,
+			newName:   "sh",
+			expected: `package main
+
+import (
+	"fmt"
+
+	sh "example.com/app/shapes"
+)
+
+func main() {
+	p := sh.New(sh.Origin, 1)
+	var q sh.Point = sh.Point{X: 2}
+	m := map[int]sh.Point{sh.Origin: p}
+	fmt.Println(p.Add(q).X, m)
+}
+`,
+		},
+		{
+			name: "package name drops the dot",
+			content: `package main
+
+import . "example.com/app/shapes"
+
+func main() {
+	_ = New(1, 2)
+}
+`,
+			line:      2,
+			character: 7,
+			newName:   "shapes",
+			expected: `package main
+
+import "example.com/app/shapes"
+
+func main() {
+	_ = shapes.New(1, 2)
+}
+`,
+		},
+		{
+			name: "shadowing local is refused",
+			content: `package main
+
+import . "example.com/app/shapes"
+
+func main() {
+	sh := 1
+	_ = New(sh, 2)
+}
+`,
+			line:      2,
+			character: 7,
+			newName:   "sh",
+			expectErr: true,
+		},
+		{
+			name: "not a dot import",
+			content: `package main
+
+import sh "example.com/app/shapes"
+
+func main() {
+	_ = sh.New(1, 2)
+}
+`,
+			line:      2,
+			character: 7,
+			newName:   "shapes",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{
+				"go.mod":           "module example.com/app\n\ngo 1.22\n",
+				"shapes/shapes.go": shapes,
+				"main.go":          tt.content,
+			}
+			for name, content := range files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("failed to create dir: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", name, err)
+				}
+			}
+
+			path := filepath.Join(dir, "main.go")
+
+			edit, err := ConvertDotImport(path, tt.line, tt.character, tt.newName)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := lsp.ApplyWorkspaceEdit(edit, false); err != nil {
+				t.Fatalf("failed to apply edit: %v", err)
+			}
+
+			result, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+
+			if string(result) != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package rename
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"go.lsp.dev/protocol"
+	"go.lsp.dev/uri"
+)
+
+// Merge collapses every named import of importPath in the file into a single
+// import named newName: the first is renamed, the others are deleted, and
+// references through any of their names are rewritten. Blank imports are
+// left alone. Merging into the package's own name leaves the import unnamed.
+func (e *ASTEngine) Merge(filePath, importPath, newName string) (*protocol.WorkspaceEdit, error) {
+	if !token.IsIdentifier(newName) {
+		return nil, fmt.Errorf("invalid alias %q: not a Go identifier", newName)
+	}
+
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	src, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, absPath, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+
+	var specs []*ast.ImportSpec
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || path != importPath {
+			continue
+		}
+		if spec.Name != nil && spec.Name.Name == "_" {
+			continue
+		}
+		if spec.Name != nil && spec.Name.Name == "." {
+			return nil, fmt.Errorf("cannot merge dot import of %s; convert it first", spec.Path.Value)
+		}
+		specs = append(specs, spec)
+	}
+
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no import of %q in %s", importPath, filePath)
+	}
+
+	info := e.check(fset, file)
+
+	var edits []protocol.TextEdit
+
+	kept := specs[0]
+	obj := importedName(info, kept)
+	if obj == nil {
+		return nil, fmt.Errorf("failed to resolve import %s", kept.Path.Value)
+	}
+
+	switch {
+	case newName == obj.Imported().Name() && kept.Name != nil:
+		edits = append(edits, textEdit(fset, kept.Name.Pos(), kept.Path.Pos(), ""))
+	case kept.Name != nil && kept.Name.Name != newName:
+		edits = append(edits, textEdit(fset, kept.Name.Pos(), kept.Name.End(), newName))
+	case kept.Name == nil && newName != obj.Imported().Name():
+		edits = append(edits, textEdit(fset, kept.Path.Pos(), kept.Path.Pos(), newName+" "))
+	}
+
+	for _, spec := range specs[1:] {
+		edits = append(edits, deleteImportSpec(fset, file, src, spec))
+	}
+
+	fileScope := info.Scopes[file]
+
+	for _, spec := range specs {
+		pkgName := importedName(info, spec)
+		if pkgName == nil {
+			return nil, fmt.Errorf("failed to resolve import %s", spec.Path.Value)
+		}
+
+		for _, ident := range references(info, pkgName) {
+			// A local declaration of newName would capture the reference
+			if scope := fileScope.Innermost(ident.Pos()); scope != nil && scope != fileScope {
+				if _, other := scope.LookupParent(newName, ident.Pos()); other != nil && isLocal(other, fileScope) {
+					return nil, fmt.Errorf("alias %q would be shadowed by local %s at line %d",
+						newName, describe(other), fset.Position(ident.Pos()).Line)
+				}
+			}
+
+			if ident.Name != newName {
+				edits = append(edits, textEdit(fset, ident.Pos(), ident.End(), newName))
+			}
+		}
+	}
+
+	return &protocol.WorkspaceEdit{
+		Changes: map[uri.URI][]protocol.TextEdit{
+			uri.File(absPath): edits,
+		},
+	}, nil
+}
+
+// isLocal reports whether obj is declared inside a function rather than at
+// file, package or universe scope
+func isLocal(obj types.Object, fileScope *types.Scope) bool {
+	parent := obj.Parent()
+	return parent != fileScope && parent != fileScope.Parent() && parent != types.Universe
+}
+
+// deleteImportSpec removes spec along with its doc comment and the lines it
+// occupies, or its whole declaration when it is the declaration's only spec.
+// Removing a declaration also takes the blank line separating it from the
+// next one.
+func deleteImportSpec(fset *token.FileSet, file *ast.File, src []byte, spec *ast.ImportSpec) protocol.TextEdit {
+	var start, end token.Pos = spec.Pos(), spec.End()
+	if spec.Doc != nil {
+		start = spec.Doc.Pos()
+	}
+	if spec.Comment != nil {
+		end = spec.Comment.End()
+	}
+
+	wholeDecl := false
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT || len(gen.Specs) != 1 || gen.Specs[0] != spec {
+			continue
+		}
+		start, end = gen.Pos(), gen.End()
+		if gen.Doc != nil {
+			start = gen.Doc.Pos()
+		}
+		wholeDecl = true
+	}
+
+	startLine := fset.Position(start).Line
+	endLine := fset.Position(end).Line
+
+	if wholeDecl {
+		lines := bytes.Split(src, []byte("\n"))
+		if endLine < len(lines) && len(bytes.TrimSpace(lines[endLine])) == 0 {
+			endLine++
+		}
+	}
+
+	return protocol.TextEdit{
+		Range: protocol.Range{
+			Start: protocol.Position{Line: uint32(startLine - 1)},
+			End:   protocol.Position{Line: uint32(endLine)},
+		},
+	}
+}
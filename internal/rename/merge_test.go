@@ -0,0 +1,165 @@
+package rename
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackchuka/goalias/internal/lsp"
+)
+
+func TestASTEngineMerge(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		newName   string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name: "merge into new alias",
+			content: `package main
+
+import (
+	"fmt"
+	foo "strings"
+	// bar is the old name
+	bar "strings"
+)
+
+func main() {
+	fmt.Println(foo.ToUpper("a"), bar.ToLower("B"))
+}
+`,
+			newName: "str",
+			expected: `package main
+
+import (
+	"fmt"
+	str "strings"
+)
+
+func main() {
+	fmt.Println(str.ToUpper("a"), str.ToLower("B"))
+}
+`,
+		},
+		{
+			name: "merge into existing name from separate declaration",
+			content: `package main
+
+import foo "strings"
+
+import bar "strings"
+
+func main() {
+	_ = foo.ToUpper(bar.ToLower("a"))
+}
+`,
+			newName: "bar",
+			expected: `package main
+
+import bar "strings"
+
+func main() {
+	_ = bar.ToUpper(bar.ToLower("a"))
+}
+`,
+		},
+		{
+			name: "merge into package name",
+			content: `package main
+
+import (
+	s "strings"
+	"strings"
+	_ "strings"
+)
+
+func main() {
+	_ = s.ToUpper(strings.ToLower("a"))
+}
+`,
+			newName: "strings",
+			expected: `package main
+
+import (
+	"strings"
+	_ "strings"
+)
+
+func main() {
+	_ = strings.ToUpper(strings.ToLower("a"))
+}
+`,
+		},
+		{
+			name: "local shadowing is refused",
+			content: `package main
+
+import (
+	foo "strings"
+	bar "strings"
+)
+
+func main() {
+	str := "a"
+	_ = foo.ToUpper(bar.ToLower(str))
+}
+`,
+			newName:   "str",
+			expectErr: true,
+		},
+		{
+			name: "dot import is refused",
+			content: `package main
+
+import (
+	foo "strings"
+	. "strings"
+)
+
+func main() {
+	_ = foo.ToUpper(ToLower("a"))
+}
+`,
+			newName:   "str",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "main.go")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			engine := &ASTEngine{PackageName: func(string) string { return "strings" }}
+			edit, err := engine.Merge(path, "strings", tt.newName)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := lsp.ApplyWorkspaceEdit(edit, false); err != nil {
+				t.Fatalf("failed to apply edit: %v", err)
+			}
+
+			result, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+
+			if string(result) != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// Package analyzer exposes the goalias import alias policy as a go/analysis
+// Analyzer, for use with go vet -vettool, multichecker binaries, golangci-lint
+// and editors.
+//
+// The policy is read from the .goalias.yaml file found by walking up from each
+// package's directory, or from the file given with the -config flag. Every
+// import breaking a rule is reported on its import spec, and where the rule
+// names a fix the diagnostic carries a suggested fix renaming the import and
+// every reference to it in the file.
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/jackchuka/goalias/internal/config"
+	"github.com/jackchuka/goalias/internal/discovery"
+	discoveryast "github.com/jackchuka/goalias/internal/discovery/ast"
+	"github.com/jackchuka/goalias/internal/policy"
+	"golang.org/x/tools/go/analysis"
+)
+
+const doc = `check import aliases against the goalias policy
+
+Reports imports whose alias breaks a rule of the .goalias.yaml policy file:
+required aliases, unaliased paths and forbidden aliases. Fixable violations
+carry a suggested fix renaming the import and its references.`
+
+// Analyzer reports imports breaking the alias policy
+var Analyzer = &analysis.Analyzer{
+	Name: "goalias",
+	Doc:  doc,
+	URL:  "https://github.com/jackchuka/goalias",
+	Run:  run,
+}
+
+var configPath string
+
+func init() {
+	Analyzer.Flags.StringVar(&configPath, "config", "", "path to the alias policy file (default: "+config.FileName+" found from each package's directory)")
+}
+
+// configs caches the policy per directory, or under the -config path. A nil
+// entry records a directory without a policy.
+var configs = struct {
+	sync.Mutex
+	byKey map[string]*config.Config
+}{byKey: make(map[string]*config.Config)}
+
+// policyFor returns the policy governing files in dir, or nil if there is none
+func policyFor(dir string) (*config.Config, error) {
+	key := dir
+	if configPath != "" {
+		key = configPath
+	}
+
+	configs.Lock()
+	defer configs.Unlock()
+
+	if cfg, ok := configs.byKey[key]; ok {
+		return cfg, nil
+	}
+
+	var (
+		cfg *config.Config
+		err error
+	)
+	if configPath != "" {
+		cfg, err = config.Load(configPath)
+	} else {
+		cfg, err = config.Discover(dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	configs.byKey[key] = cfg
+	return cfg, nil
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	for _, file := range pass.Files {
+		if ast.IsGenerated(file) {
+			continue
+		}
+
+		filename := pass.Fset.Position(file.Pos()).Filename
+
+		cfg, err := policyFor(filepath.Dir(filename))
+		if err != nil {
+			return nil, err
+		}
+		if cfg == nil {
+			continue
+		}
+
+		for _, spec := range file.Imports {
+			checkImport(pass, file, filename, spec, cfg)
+		}
+	}
+
+	return nil, nil
+}
+
+// checkImport reports spec if it breaks a rule of cfg
+func checkImport(pass *analysis.Pass, file *ast.File, filename string, spec *ast.ImportSpec, cfg *config.Config) {
+	pkgName := pass.TypesInfo.PkgNameOf(spec)
+	if pkgName == nil {
+		return
+	}
+
+	importPath, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return
+	}
+
+	// The type checker already knows the package name, so the policy needs
+	// no go list invocation to resolve it
+	name := pkgName.Imported().Name()
+	discovery.RecordPackageName(importPath, name)
+
+	info := &discoveryast.ImportInfo{
+		Position:    pass.Fset.Position(spec.Pos()),
+		Path:        importPath,
+		Found:       true,
+		FilePackage: file.Name.Name,
+	}
+	alias := name
+	if spec.Name != nil {
+		info.Alias = spec.Name.Name
+		alias = spec.Name.Name
+	}
+
+	v, ok := policy.CheckImport(discovery.ImportResult{
+		File:       filename,
+		Location:   fmt.Sprintf("%s:%d", filename, info.Position.Line),
+		ImportPath: importPath,
+		Package:    pass.Pkg.Path(),
+		Alias:      alias,
+		Info:       info,
+	}, cfg)
+	if !ok {
+		return
+	}
+
+	diagnostic := analysis.Diagnostic{
+		Pos:      spec.Pos(),
+		End:      spec.End(),
+		Category: v.RuleID(),
+		Message:  v.Message(),
+	}
+
+	// Dot imports need every reference qualified, which goalias set
+	// --dot-imports=convert does
+	if v.Fixable() && info.Alias != "." {
+		if fix, ok := renameFix(pass, file, spec, pkgName, v.Required); ok {
+			diagnostic.SuggestedFixes = []analysis.SuggestedFix{fix}
+		}
+	}
+
+	pass.Report(diagnostic)
+}
+
+// renameFix returns the edits renaming the import declared by spec to newName
+// along with every reference to it. It returns false when newName would
+// collide with or be shadowed by another declaration.
+func renameFix(pass *analysis.Pass, file *ast.File, spec *ast.ImportSpec, pkgName *types.PkgName, newName string) (analysis.SuggestedFix, bool) {
+	if collides(pass, file, pkgName, newName) {
+		return analysis.SuggestedFix{}, false
+	}
+
+	message := fmt.Sprintf("Rename import to %s", newName)
+
+	var edits []analysis.TextEdit
+	switch {
+	case newName == pkgName.Imported().Name() && spec.Name != nil:
+		// Drop the explicit name along with the space before the path
+		message = "Remove import alias"
+		edits = append(edits, analysis.TextEdit{Pos: spec.Name.Pos(), End: spec.Path.Pos()})
+	case spec.Name != nil:
+		edits = append(edits, analysis.TextEdit{Pos: spec.Name.Pos(), End: spec.Name.End(), NewText: []byte(newName)})
+	default:
+		edits = append(edits, analysis.TextEdit{Pos: spec.Path.Pos(), End: spec.Path.Pos(), NewText: []byte(newName + " ")})
+	}
+
+	for ident, obj := range pass.TypesInfo.Uses {
+		if obj == pkgName {
+			edits = append(edits, analysis.TextEdit{Pos: ident.Pos(), End: ident.End(), NewText: []byte(newName)})
+		}
+	}
+
+	return analysis.SuggestedFix{Message: message, TextEdits: edits}, true
+}
+
+// collides reports whether naming the import pkgName newName in file would
+// clash with another import or package-level declaration, capture a
+// reference to a predeclared identifier, or be shadowed at a reference
+func collides(pass *analysis.Pass, file *ast.File, pkgName *types.PkgName, newName string) bool {
+	fileScope := pass.TypesInfo.Scopes[file]
+	if fileScope == nil {
+		return true
+	}
+
+	if obj := fileScope.Lookup(newName); obj != nil && obj != pkgName {
+		return true
+	}
+	if pass.Pkg.Scope().Lookup(newName) != nil {
+		return true
+	}
+
+	for ident, obj := range pass.TypesInfo.Uses {
+		if ident.Pos() < file.Pos() || ident.Pos() > file.End() {
+			continue
+		}
+
+		if obj.Parent() == types.Universe && ident.Name == newName {
+			return true
+		}
+
+		if obj != pkgName {
+			continue
+		}
+
+		scope := fileScope.Innermost(ident.Pos())
+		if scope == nil {
+			continue
+		}
+		if _, other := scope.LookupParent(newName, ident.Pos()); other != nil && other != pkgName && other.Parent() != types.Universe {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,21 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	testdata := analysistest.TestData()
+
+	if err := Analyzer.Flags.Set("config", filepath.Join(testdata, "goalias.yaml")); err != nil {
+		t.Fatalf("failed to set config flag: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = Analyzer.Flags.Set("config", "")
+	})
+
+	analysistest.RunWithSuggestedFixes(t, testdata, Analyzer, "a")
+}
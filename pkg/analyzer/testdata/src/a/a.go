@@ -0,0 +1,18 @@
+package a
+
+import (
+	"bytes"     // want `import "bytes" is aliased "bytes", want "buf"`
+	f "fmt"     // want `import "fmt" must not be aliased, got "f"`
+	s "strings" // want `import "strings" is aliased "s", want "str"`
+	x "unicode" // want `alias "x" for import "unicode" is forbidden: too short`
+)
+
+func A() {
+	f.Println(s.ToUpper("a"), x.IsUpper('A'))
+	f.Println(s.ToLower("B"))
+}
+
+func B() {
+	buf := []byte("a")
+	_ = bytes.ToUpper(buf)
+}
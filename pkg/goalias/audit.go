@@ -0,0 +1,157 @@
+package goalias
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/jackchuka/goalias/internal/audit"
+	"github.com/jackchuka/goalias/internal/config"
+	"github.com/jackchuka/goalias/internal/discovery"
+)
+
+// AuditEntry summarises every name an import path is used under
+type AuditEntry struct {
+	Path string `json:"importPath"`
+	// PackageName is the declared name of the imported package
+	PackageName string `json:"packageName"`
+	// Usages are ordered by descending count, then alias
+	Usages []AliasUsage `json:"usages"`
+	Total  int          `json:"total"`
+}
+
+// AliasUsage is one name an import path is used under
+type AliasUsage struct {
+	// Alias is the name the imports refer to the package by: the declared
+	// alias, or the package name for unnamed imports
+	Alias string `json:"alias"`
+	// Count is the number of import specs using Alias
+	Count int `json:"count"`
+	// Explicit is the number of those specs that declare Alias explicitly
+	Explicit int `json:"explicit"`
+	// Examples are the first few import specs using Alias
+	Examples []Location `json:"examples"`
+}
+
+// Location is the 1-based position of an import spec
+type Location struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Inconsistent reports whether the path is used under more than one name
+func (e AuditEntry) Inconsistent() bool {
+	return e.internal().Inconsistent()
+}
+
+// Aliased reports whether any import of the path declares an alias
+func (e AuditEntry) Aliased() bool {
+	return e.internal().Aliased()
+}
+
+func newAuditEntry(e audit.Entry) AuditEntry {
+	entry := AuditEntry{Path: e.ImportPath, PackageName: e.PackageName, Total: e.Total}
+	for _, u := range e.Usages {
+		usage := AliasUsage{Alias: u.Alias, Count: u.Count, Explicit: u.Explicit}
+		for _, l := range u.Examples {
+			usage.Examples = append(usage.Examples, Location(l))
+		}
+		entry.Usages = append(entry.Usages, usage)
+	}
+	return entry
+}
+
+func (e AuditEntry) internal() audit.Entry {
+	entry := audit.Entry{ImportPath: e.Path, PackageName: e.PackageName, Total: e.Total}
+	for _, u := range e.Usages {
+		usage := audit.Usage{Alias: u.Alias, Count: u.Count, Explicit: u.Explicit}
+		for _, l := range u.Examples {
+			usage.Examples = append(usage.Examples, audit.Location(l))
+		}
+		entry.Usages = append(entry.Usages, usage)
+	}
+	return entry
+}
+
+// Audit groups every import in the files selected by opts by import path and
+// by the name it is used under, keeping up to examples locations per name.
+// Blank imports bind no name and are ignored. Entries are ordered by import
+// path.
+func Audit(ctx context.Context, opts Options, examples int) ([]AuditEntry, error) {
+	imports, err := Scan(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]discovery.ImportResult, 0, len(imports))
+	for _, imp := range imports {
+		results = append(results, imp.result())
+	}
+
+	var entries []AuditEntry
+	for _, e := range audit.Build(results, examples) {
+		entries = append(entries, newAuditEntry(e))
+	}
+
+	return entries, nil
+}
+
+// Suggestion is the proposed canonical alias for an import path
+type Suggestion struct {
+	Path  string `json:"importPath"`
+	Alias string `json:"alias"`
+	// Votes is the number of imports already using Alias
+	Votes int `json:"votes"`
+	// Total is the number of imports of the path that bind a name
+	Total int `json:"total"`
+	// Alternatives are the other names in use, most used first
+	Alternatives []string `json:"alternatives,omitempty"`
+}
+
+// Suggest proposes a canonical alias for each entry by majority vote. Ties go
+// to the name following Go conventions: lowercase, without underscores, and
+// not shadowing a standard library package, then to the package's own name,
+// then alphabetically. Dot imports do not vote.
+func Suggest(ctx context.Context, entries []AuditEntry) ([]Suggestion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	std, err := discovery.StandardPackages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list standard library packages: %w", err)
+	}
+
+	converted := make([]audit.Entry, 0, len(entries))
+	for _, e := range entries {
+		converted = append(converted, e.internal())
+	}
+
+	var suggestions []Suggestion
+	for _, s := range audit.Suggest(converted, std) {
+		suggestions = append(suggestions, Suggestion{
+			Path:         s.ImportPath,
+			Alias:        s.Alias,
+			Votes:        s.Votes,
+			Total:        s.Total,
+			Alternatives: s.Alternatives,
+		})
+	}
+
+	return suggestions, nil
+}
+
+// WriteSuggestedPolicy writes suggestions to w as a policy file with one
+// required alias rule each
+func WriteSuggestedPolicy(w io.Writer, suggestions []Suggestion) error {
+	cfg := &config.Config{}
+	for _, s := range suggestions {
+		cfg.Rules = append(cfg.Rules, config.Rule{Path: s.Path, Alias: s.Alias})
+	}
+
+	if _, err := io.WriteString(w, "# Generated by goalias suggest from current usage; review before applying\n"); err != nil {
+		return err
+	}
+	return config.Encode(w, cfg)
+}
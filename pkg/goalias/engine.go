@@ -0,0 +1,257 @@
+package goalias
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/jackchuka/goalias/internal/lsp"
+	"github.com/jackchuka/goalias/internal/rename"
+	"go.lsp.dev/protocol"
+)
+
+// Engine computes the edits that rename the import spec at a 0-based
+// line/character position, along with every reference to it. Characters
+// count bytes. Renaming to the package's own name should drop the explicit
+// name.
+type Engine interface {
+	Rename(ctx context.Context, filePath string, line, character int, newName string) (*protocol.WorkspaceEdit, error)
+}
+
+// EngineFunc adapts an ordinary function to Engine
+type EngineFunc func(ctx context.Context, filePath string, line, character int, newName string) (*protocol.WorkspaceEdit, error)
+
+// Rename calls f
+func (f EngineFunc) Rename(ctx context.Context, filePath string, line, character int, newName string) (*protocol.WorkspaceEdit, error) {
+	return f(ctx, filePath, line, character, newName)
+}
+
+// ASTEngine returns the built-in engine, which renames imports using go/ast
+// and go/types without a language server. Each file is type-checked on its
+// own, so it also renames files excluded by build constraints.
+func ASTEngine() Engine {
+	engine := rename.NewASTEngine()
+	return EngineFunc(func(ctx context.Context, filePath string, line, character int, newName string) (*protocol.WorkspaceEdit, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return engine.Rename(filePath, line, character, newName)
+	})
+}
+
+// GoplsOptions configures the gopls process StartGopls runs
+type GoplsOptions struct {
+	// Path is the gopls binary. Empty means gopls from $PATH.
+	Path string
+	// Args are extra arguments to gopls serve, such as -remote=auto or
+	// -logfile
+	Args []string
+	// Env overrides variables of the inherited environment, such as GOFLAGS
+	Env map[string]string
+	// Settings is the gopls settings block, such as buildFlags, sent as
+	// initializationOptions and in answer to workspace/configuration
+	Settings map[string]any
+
+	// LoadTimeout bounds the wait for gopls to load the workspace in
+	// StartGopls. Zero means DefaultGoplsLoadTimeout.
+	LoadTimeout time.Duration
+	// RequestTimeout bounds each rename. Zero means
+	// DefaultGoplsRequestTimeout.
+	RequestTimeout time.Duration
+	// Progress, if set, receives the work done progress gopls reports, such
+	// as its initial workspace load. It must not block.
+	Progress func(GoplsProgress)
+
+	// Stderr receives the standard error of gopls. Nil discards it.
+	Stderr io.Writer
+	// Trace, if set, receives every JSON-RPC message exchanged with gopls,
+	// one timestamped line each, marked --> when sent and <-- when received
+	Trace io.Writer
+	// Log, if set, receives the messages gopls logs and shows to the user.
+	// It must not block.
+	Log func(protocol.MessageType, string)
+
+	// MaxRestarts is how many times the engine restarts gopls after it
+	// exits unexpectedly, retrying the rename in flight. Zero fails the
+	// rename with ErrGoplsExited instead.
+	MaxRestarts int
+	// Restarting, if set, is called with the reason before each restart
+	Restarting func(cause error)
+}
+
+// ErrGoplsExited is returned by GoplsEngine.Rename when gopls exits or closes
+// the connection and cannot be restarted
+var ErrGoplsExited = lsp.ErrServerExited
+
+// Default gopls timeouts
+const (
+	DefaultGoplsLoadTimeout    = lsp.DefaultLoadTimeout
+	DefaultGoplsRequestTimeout = lsp.DefaultRequestTimeout
+)
+
+// GoplsProgress is a work done progress report of gopls
+type GoplsProgress struct {
+	// Kind is begin, report or end
+	Kind string
+	// Title is given when the progress begins and repeated in its reports
+	Title   string
+	Message string
+	// Percentage is nil when gopls does not report one
+	Percentage *uint32
+}
+
+func (o GoplsOptions) client() lsp.Options {
+	opts := lsp.Options{
+		Path:           o.Path,
+		Args:           o.Args,
+		Env:            o.Env,
+		Settings:       o.Settings,
+		LoadTimeout:    o.LoadTimeout,
+		RequestTimeout: o.RequestTimeout,
+		Stderr:         o.Stderr,
+		Trace:          o.Trace,
+		Log:            o.Log,
+	}
+	if o.Progress != nil {
+		opts.Progress = func(p lsp.Progress) { o.Progress(GoplsProgress(p)) }
+	}
+	return opts
+}
+
+// Merge returns o overridden by override: a non-empty Path, non-zero timeouts
+// and MaxRestarts, and non-nil writers and callbacks replace o's, Args are
+// appended, and Env and Settings entries replace o's key by key
+func (o GoplsOptions) Merge(override GoplsOptions) GoplsOptions {
+	merged := o
+	merged.Args = append(append([]string(nil), o.Args...), override.Args...)
+	merged.Env, merged.Settings = nil, nil
+
+	if override.Path != "" {
+		merged.Path = override.Path
+	}
+	if override.LoadTimeout != 0 {
+		merged.LoadTimeout = override.LoadTimeout
+	}
+	if override.RequestTimeout != 0 {
+		merged.RequestTimeout = override.RequestTimeout
+	}
+	if override.Progress != nil {
+		merged.Progress = override.Progress
+	}
+	if override.Stderr != nil {
+		merged.Stderr = override.Stderr
+	}
+	if override.Trace != nil {
+		merged.Trace = override.Trace
+	}
+	if override.Log != nil {
+		merged.Log = override.Log
+	}
+	if override.MaxRestarts != 0 {
+		merged.MaxRestarts = override.MaxRestarts
+	}
+	if override.Restarting != nil {
+		merged.Restarting = override.Restarting
+	}
+
+	if len(o.Env)+len(override.Env) > 0 {
+		merged.Env = make(map[string]string, len(o.Env)+len(override.Env))
+		maps.Copy(merged.Env, o.Env)
+		maps.Copy(merged.Env, override.Env)
+	}
+
+	if len(o.Settings)+len(override.Settings) > 0 {
+		merged.Settings = make(map[string]any, len(o.Settings)+len(override.Settings))
+		maps.Copy(merged.Settings, o.Settings)
+		maps.Copy(merged.Settings, override.Settings)
+	}
+
+	return merged
+}
+
+// GoplsEngine renames imports through a gopls process. Only files in the
+// current build configuration can be renamed.
+type GoplsEngine struct {
+	dir  string
+	opts GoplsOptions
+
+	mu       sync.Mutex
+	client   *lsp.Client
+	restarts int
+}
+
+// StartGopls starts and initializes gopls for the workspace rooted at dir and
+// waits for it to load the workspace. The engine must be closed to stop the
+// process.
+func StartGopls(ctx context.Context, dir string, opts GoplsOptions) (*GoplsEngine, error) {
+	client, err := startGopls(ctx, dir, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GoplsEngine{dir: dir, opts: opts, client: client}, nil
+}
+
+func startGopls(ctx context.Context, dir string, opts GoplsOptions) (*lsp.Client, error) {
+	client, err := lsp.NewClient(dir, opts.client())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LSP client: %w", err)
+	}
+
+	if err := client.Initialize(ctx); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("failed to initialize LSP client: %w", err)
+	}
+
+	return client, nil
+}
+
+// Rename asks gopls to rename the import spec at the position. If gopls
+// exits, it is restarted up to MaxRestarts times over the engine's life and
+// the rename is retried; renames already returned stay valid because nothing
+// is written until the plan is applied.
+func (g *GoplsEngine) Rename(ctx context.Context, filePath string, line, character int, newName string) (*protocol.WorkspaceEdit, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for {
+		edit, err := g.client.Rename(ctx, filePath, line, character, newName)
+		if err == nil || !errors.Is(err, ErrGoplsExited) || g.restarts >= g.opts.MaxRestarts {
+			return edit, err
+		}
+
+		if err := g.restart(ctx, err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// restart replaces a gopls that exited with cause by a new process
+func (g *GoplsEngine) restart(ctx context.Context, cause error) error {
+	g.restarts++
+	if g.opts.Restarting != nil {
+		g.opts.Restarting(cause)
+	}
+
+	_ = g.client.Close()
+
+	client, err := startGopls(ctx, g.dir, g.opts)
+	if err != nil {
+		return fmt.Errorf("failed to restart gopls after %v: %w", cause, err)
+	}
+
+	g.client = client
+	return nil
+}
+
+// Close stops gopls
+func (g *GoplsEngine) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.client.Close()
+}
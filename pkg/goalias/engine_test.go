@@ -0,0 +1,124 @@
+package goalias
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGoplsEnv makes the test binary act as a gopls that exits on its first
+// rename unless the file the variable names exists, which it creates
+const fakeGoplsEnv = "GOALIAS_FAKE_GOPLS_MARKER"
+
+func TestMain(m *testing.M) {
+	if marker := os.Getenv(fakeGoplsEnv); marker != "" {
+		serveFakeGopls(marker)
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+func serveFakeGopls(marker string) {
+	reader := bufio.NewReader(os.Stdin)
+	send := func(msg string) {
+		fmt.Printf("Content-Length: %d\r\n\r\n%s", len(msg), msg)
+	}
+
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			os.Exit(1)
+		}
+		length, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+		_, _ = reader.ReadString('\n')
+		content := make([]byte, length)
+		if _, err := io.ReadFull(reader, content); err != nil {
+			os.Exit(1)
+		}
+
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		_ = json.Unmarshal(content, &msg)
+
+		switch msg.Method {
+		case "initialize":
+			send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{"capabilities":{"positionEncoding":"utf-8"}}}`, msg.ID))
+		case "initialized":
+			send(`{"jsonrpc":"2.0","method":"$/progress","params":{"token":1,"value":{"kind":"begin","title":"Setting up workspace"}}}`)
+			send(`{"jsonrpc":"2.0","method":"$/progress","params":{"token":1,"value":{"kind":"end"}}}`)
+		case "textDocument/rename":
+			if _, err := os.Stat(marker); err != nil {
+				_ = os.WriteFile(marker, nil, 0644)
+				fmt.Fprintln(os.Stderr, "panic: rename crashed")
+				os.Exit(2)
+			}
+			send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":{}}`, msg.ID))
+		case "shutdown":
+			send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%s,"result":null}`, msg.ID))
+		case "exit":
+			os.Exit(0)
+		}
+	}
+}
+
+func TestGoplsEngineRestarts(t *testing.T) {
+	executable, err := os.Executable()
+	if err != nil {
+		t.Skipf("cannot locate the test binary: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		maxRestarts int
+		wantErr     bool
+	}{
+		{name: "restart disabled", maxRestarts: 0, wantErr: true},
+		{name: "restart and retry", maxRestarts: 1, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			var causes []error
+			engine, err := StartGopls(context.Background(), dir, GoplsOptions{
+				Path:        executable,
+				Env:         map[string]string{fakeGoplsEnv: filepath.Join(dir, "crashed")},
+				LoadTimeout: 5 * time.Second,
+				MaxRestarts: tt.maxRestarts,
+				Restarting:  func(cause error) { causes = append(causes, cause) },
+			})
+			if err != nil {
+				t.Fatalf("failed to start fake gopls: %v", err)
+			}
+			defer func() { _ = engine.Close() }()
+
+			_, err = engine.Rename(context.Background(), filepath.Join(dir, "a.go"), 2, 7, "u")
+			if tt.wantErr {
+				if !errors.Is(err, ErrGoplsExited) || !strings.Contains(err.Error(), "rename crashed") {
+					t.Errorf("expected ErrGoplsExited with the crash output, got %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(causes) != 1 || !errors.Is(causes[0], ErrGoplsExited) {
+				t.Errorf("expected one restart after the crash, got %v", causes)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// Package goalias is the library behind the goalias command. It finds import
+// specs, checks them against an alias policy, plans the edits that fix them
+// and applies those edits atomically.
+//
+// A typical migration scans and checks, plans with a rename engine, and then
+// applies the planned changes:
+//
+//	violations, err := goalias.Check(ctx, goalias.Options{Tests: true}, policy)
+//	changes, err := goalias.Plan(ctx, violations, goalias.PlanOptions{})
+//	err = goalias.Apply(ctx, changes)
+package goalias
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackchuka/goalias/internal/discovery"
+	"github.com/jackchuka/goalias/internal/discovery/ast"
+)
+
+// Options selects the packages and files to scan
+type Options struct {
+	// Patterns are go list package patterns. Empty means ./...
+	Patterns []string
+
+	// Tests includes _test.go files of each package and its external test
+	// package
+	Tests bool
+	// AllBuildTags includes files excluded by build constraints
+	AllBuildTags bool
+
+	// Tags, GOOS and GOARCH form a matrix of build contexts whose file sets
+	// are unioned. An empty GOOS or GOARCH list uses the environment's value.
+	Tags   []string
+	GOOS   []string
+	GOARCH []string
+}
+
+func (o Options) patterns() []string {
+	return discovery.GetPatterns(o.Patterns)
+}
+
+func (o Options) discovery() discovery.Options {
+	return discovery.Options{
+		Tests:        o.Tests,
+		AllBuildTags: o.AllBuildTags,
+		Tags:         o.Tags,
+		GOOS:         o.GOOS,
+		GOARCH:       o.GOARCH,
+	}
+}
+
+// Import is an import spec found in a source file
+type Import struct {
+	// File is the absolute path of the file containing the import
+	File string
+	// Line and Column are the 1-based position of the import spec; columns
+	// count bytes
+	Line   int
+	Column int
+
+	// Path is the imported package path
+	Path string
+	// Name is the name written in the import spec, such as "u", "_" or ".",
+	// or empty when the import is unnamed
+	Name string
+	// Alias is the name the file refers to the package by: Name, or the
+	// package's declared name when the import is unnamed
+	Alias string
+
+	// Package is the import path of the package containing File
+	Package string
+	// FilePackage is the package clause of File
+	FilePackage string
+	// BuildIgnored is set when File is excluded by build constraints in the
+	// default build context
+	BuildIgnored bool
+}
+
+// Location returns the import's file and line as "file:line"
+func (i Import) Location() string {
+	return fmt.Sprintf("%s:%d", i.File, i.Line)
+}
+
+// Explicit reports whether the import spec names the package
+func (i Import) Explicit() bool {
+	return i.Name != ""
+}
+
+func newImport(r discovery.ImportResult) Import {
+	return Import{
+		File:         r.File,
+		Line:         r.Info.Position.Line,
+		Column:       r.Info.Position.Column,
+		Path:         r.ImportPath,
+		Name:         r.Info.Alias,
+		Alias:        r.Alias,
+		Package:      r.Package,
+		FilePackage:  r.Info.FilePackage,
+		BuildIgnored: r.BuildIgnored,
+	}
+}
+
+// result converts i back to the form the internal packages use
+func (i Import) result() discovery.ImportResult {
+	info := &ast.ImportInfo{
+		Path:        i.Path,
+		Alias:       i.Name,
+		Found:       true,
+		FilePackage: i.FilePackage,
+	}
+	info.Position.Filename = i.File
+	info.Position.Line = i.Line
+	info.Position.Column = i.Column
+
+	return discovery.ImportResult{
+		File:         i.File,
+		Location:     i.Location(),
+		ImportPath:   i.Path,
+		Package:      i.Package,
+		Alias:        i.Alias,
+		Info:         info,
+		BuildIgnored: i.BuildIgnored,
+	}
+}
+
+// Scan returns every import of importPaths in the files selected by opts,
+// ordered by file and then position. With no importPaths, every import is
+// returned. Blank and dot imports are included. Package listing is not
+// interruptible; ctx is checked before and after it.
+func Scan(ctx context.Context, opts Options, importPaths ...string) ([]Import, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var (
+		results []discovery.ImportResult
+		err     error
+	)
+	if len(importPaths) == 0 {
+		results, err = discovery.FindAllImports(opts.patterns(), opts.discovery())
+	} else {
+		results, err = discovery.FindImports(opts.patterns(), importPaths, opts.discovery())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	imports := make([]Import, 0, len(results))
+	for _, r := range results {
+		imports = append(imports, newImport(r))
+	}
+
+	return imports, nil
+}
+
+// PackageName returns the declared name of the package at importPath, which
+// is the name an unnamed import refers to it by. Paths that cannot be loaded
+// fall back to a guess from the last path element.
+func PackageName(importPath string) string {
+	return discovery.DefaultAlias(importPath)
+}
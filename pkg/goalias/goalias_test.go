@@ -0,0 +1,395 @@
+package goalias
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.lsp.dev/protocol"
+)
+
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/app\n\ngo 1.22\n"
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	return dir
+}
+
+func TestScan(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"util/util.go": "package util\n\nfunc Do() {}\n",
+		"a/a.go":       "package a\n\nimport (\n\t\"fmt\"\n\tu \"example.com/app/util\"\n)\n\nfunc A() { fmt.Println(); u.Do() }\n",
+	})
+	t.Chdir(dir)
+
+	imports, err := Scan(context.Background(), Options{}, "example.com/app/util")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(imports) != 1 {
+		t.Fatalf("expected 1 import, got %+v", imports)
+	}
+
+	imp := imports[0]
+	if imp.Name != "u" || imp.Alias != "u" || !imp.Explicit() || imp.Line != 5 || imp.Column != 2 || imp.Package != "example.com/app/a" {
+		t.Errorf("unexpected import %+v", imp)
+	}
+
+	all, err := Scan(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(all) != 2 || all[0].Path != "fmt" || all[0].Explicit() {
+		t.Errorf("expected every import in source order, got %+v", all)
+	}
+}
+
+func TestScanCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Scan(ctx, Options{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCheckPlanApply(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"util/util.go": "package util\n\nfunc Do() {}\n",
+		"a/a.go":       "package a\n\nimport util \"example.com/app/util\"\n\nfunc A() { util.Do() }\n",
+		"b/b.go":       "package b\n\nimport x \"example.com/app/util\"\n\nfunc B() { x.Do() }\n",
+		"c/c.go":       "package c\n\nimport x \"example.com/app/util\"\n\nfunc C() { var u int; _ = u; x.Do() }\n",
+	})
+	t.Chdir(dir)
+
+	p, err := ParsePolicy([]byte("rules:\n  - path: example.com/app/util\n    alias: u\n"), dir)
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+
+	violations, err := Check(context.Background(), Options{}, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 3 {
+		t.Fatalf("expected 3 violations, got %+v", violations)
+	}
+	for _, v := range violations {
+		if v.Rule != RuleRequiredAlias || v.Required != "u" || !v.Fixable() {
+			t.Errorf("unexpected violation %+v", v)
+		}
+	}
+
+	if _, err := Plan(context.Background(), violations, PlanOptions{}); err == nil {
+		t.Fatalf("expected a conflict error")
+	} else {
+		var conflictErr *ConflictError
+		if !errors.As(err, &conflictErr) || conflictErr.Files != 1 {
+			t.Errorf("expected a ConflictError for 1 file, got %v", err)
+		}
+	}
+
+	var notices []Notice
+	var progress []string
+	changes, err := Plan(context.Background(), violations, PlanOptions{
+		OnConflict: ConflictSkip,
+		Progress: func(i, n int, v Violation) {
+			progress = append(progress, filepath.Base(v.File))
+		},
+		Notify: func(n Notice) {
+			notices = append(notices, n)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Join(progress, ",") != "a.go,b.go" {
+		t.Errorf("unexpected progress %v", progress)
+	}
+	if len(notices) != 2 || notices[0].Kind != NoticeConflict || notices[1].Kind != NoticeConflictSkipped {
+		t.Errorf("expected a conflict and a skip notice, got %+v", notices)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changed files, got %d", len(changes))
+	}
+	if !strings.Contains(changes[0].Diff(3), "+import u \"example.com/app/util\"") {
+		t.Errorf("unexpected diff:\n%s", changes[0].Diff(3))
+	}
+
+	// Planning leaves the tree untouched
+	content, err := os.ReadFile(filepath.Join(dir, "b", "b.go"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if strings.Contains(string(content), "u.Do") {
+		t.Fatalf("Plan modified b.go")
+	}
+
+	if err := Apply(context.Background(), changes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err = os.ReadFile(filepath.Join(dir, "b", "b.go"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	expected := "package b\n\nimport u \"example.com/app/util\"\n\nfunc B() { u.Do() }\n"
+	if string(content) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, content)
+	}
+}
+
+func TestPlanConflictInDuplicateImports(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"util/util.go": "package util\n\nfunc Do() {}\n",
+		"a/a.go":       "package a\n\nimport (\n\tx \"example.com/app/util\"\n\ty \"example.com/app/util\"\n)\n\nfunc A() { var u int; _ = u; x.Do(); y.Do() }\n",
+	})
+	t.Chdir(dir)
+
+	p, err := ParsePolicy([]byte("rules:\n  - path: example.com/app/util\n    alias: u\n"), dir)
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+
+	violations, err := Check(context.Background(), Options{}, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 2 {
+		t.Fatalf("expected a violation per import, got %+v", violations)
+	}
+
+	var notices []Notice
+	_, err = Plan(context.Background(), violations, PlanOptions{
+		Notify: func(n Notice) { notices = append(notices, n) },
+	})
+
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) || conflictErr.Files != 1 {
+		t.Errorf("expected a ConflictError for 1 file, got %v", err)
+	}
+	if len(notices) != 1 || notices[0].Kind != NoticeConflict || notices[0].Position.Line != 8 {
+		t.Errorf("expected one notice for the local u, got %+v", notices)
+	}
+
+	notices = nil
+	var progress []string
+	changes, err := Plan(context.Background(), violations, PlanOptions{
+		OnConflict: ConflictFallback,
+		Progress: func(i, n int, v Violation) {
+			progress = append(progress, fmt.Sprintf("%d/%d", i, n))
+		},
+		Notify: func(n Notice) { notices = append(notices, n) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Both imports are merged in one step
+	if strings.Join(progress, ",") != "1/1" {
+		t.Errorf("unexpected progress %v", progress)
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 changed file, got %d", len(changes))
+	}
+	expected := "package a\n\nimport (\n\tu2 \"example.com/app/util\"\n)\n\nfunc A() { var u int; _ = u; u2.Do(); u2.Do() }\n"
+	if string(changes[0].Modified) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, changes[0].Modified)
+	}
+}
+
+func TestPlanRenamesBuildIgnoredFilesWithAST(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"util/util.go": "package util\n\nfunc Do() {}\n",
+		"a/a.go":       "package a\n\nimport x \"example.com/app/util\"\n\nfunc A() { x.Do() }\n",
+		"a/a_other.go": "//go:build goalias_other\n\npackage a\n\nimport x \"example.com/app/util\"\n\nfunc B() { x.Do() }\n",
+		"b/b_other.go": "//go:build goalias_other\n\npackage b\n\nimport . \"example.com/app/util\"\n\nfunc B() { Do() }\n",
+		"b/doc.go":     "package b\n",
+	})
+	t.Chdir(dir)
+
+	p, err := ParsePolicy([]byte("rules:\n  - path: example.com/app/util\n    alias: u\n"), dir)
+	if err != nil {
+		t.Fatalf("failed to parse policy: %v", err)
+	}
+
+	violations, err := Check(context.Background(), Options{AllBuildTags: true}, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An engine that only sees the current build configuration, like gopls
+	engine := EngineFunc(func(ctx context.Context, filePath string, line, character int, newName string) (*protocol.WorkspaceEdit, error) {
+		if strings.HasSuffix(filePath, "_other.go") {
+			return nil, errors.New("no package for file")
+		}
+		return ASTEngine().Rename(ctx, filePath, line, character, newName)
+	})
+
+	changes, err := Plan(context.Background(), violations, PlanOptions{Engine: engine})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, c := range changes {
+		got[filepath.Base(c.Path)] = string(c.Modified)
+	}
+	if want := "//go:build goalias_other\n\npackage a\n\nimport u \"example.com/app/util\"\n\nfunc B() { u.Do() }\n"; got["a_other.go"] != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got["a_other.go"])
+	}
+	if len(changes) != 2 {
+		t.Errorf("expected a.go and a_other.go to change, got %d files", len(changes))
+	}
+
+	// A dot import cannot be converted without loading its package
+	_, err = Plan(context.Background(), violations, PlanOptions{Engine: engine, ConvertDotImports: true})
+	if err == nil || !strings.Contains(err.Error(), "b_other.go") {
+		t.Errorf("expected converting the dot import in b_other.go to fail, got %v", err)
+	}
+}
+
+func TestPlanSkipsDotImports(t *testing.T) {
+	v := Violation{
+		Import:   Import{File: "a.go", Line: 3, Column: 8, Path: "example.com/app/util", Name: ".", Alias: "."},
+		Rule:     RuleRequiredAlias,
+		Required: "u",
+	}
+
+	var notices []Notice
+	changes, err := Plan(context.Background(), []Violation{v}, PlanOptions{
+		Notify: func(n Notice) { notices = append(notices, n) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(changes) != 0 {
+		t.Errorf("expected no changes, got %d", len(changes))
+	}
+	if len(notices) != 1 || notices[0].Kind != NoticeDotImportSkipped {
+		t.Errorf("expected a dot import notice, got %+v", notices)
+	}
+}
+
+func TestAuditSuggest(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"util/util.go": "package util\n\nfunc Do() {}\n",
+		"a/a.go":       "package a\n\nimport u \"example.com/app/util\"\n\nfunc A() { u.Do() }\n",
+		"b/b.go":       "package b\n\nimport u \"example.com/app/util\"\n\nfunc B() { u.Do() }\n",
+		"c/c.go":       "package c\n\nimport \"example.com/app/util\"\n\nfunc C() { util.Do() }\n",
+	})
+	t.Chdir(dir)
+
+	entries, err := Audit(context.Background(), Options{}, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %+v", entries)
+	}
+	e := entries[0]
+	if e.Path != "example.com/app/util" || e.PackageName != "util" || e.Total != 3 || !e.Inconsistent() || !e.Aliased() {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if len(e.Usages) != 2 || e.Usages[0].Alias != "u" || e.Usages[0].Count != 2 || len(e.Usages[0].Examples) != 1 {
+		t.Errorf("unexpected usages %+v", e.Usages)
+	}
+
+	suggestions, err := Suggest(context.Background(), entries)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(suggestions) != 1 || suggestions[0].Alias != "u" || suggestions[0].Votes != 2 || suggestions[0].Total != 3 {
+		t.Errorf("unexpected suggestions %+v", suggestions)
+	}
+
+	var policy strings.Builder
+	if err := WriteSuggestedPolicy(&policy, suggestions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p, err := ParsePolicy([]byte(policy.String()), dir)
+	if err != nil {
+		t.Fatalf("failed to parse the suggested policy:\n%s\n%v", policy.String(), err)
+	}
+	violations, err := Check(context.Background(), Options{}, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 1 || filepath.Base(violations[0].File) != "c.go" {
+		t.Errorf("expected the suggested policy to flag c.go, got %+v", violations)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	target, err := ParseTarget("example.com/app/util=u")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if target != (Target{Path: "example.com/app/util", Alias: "u"}) {
+		t.Errorf("unexpected target %+v", target)
+	}
+
+	if _, err := ParseTarget("example.com/app/util=not-valid"); err == nil {
+		t.Errorf("expected an invalid alias error")
+	}
+}
+
+func TestGoplsOptionsMerge(t *testing.T) {
+	base := GoplsOptions{
+		Path:     "/opt/gopls",
+		Args:     []string{"-remote=auto"},
+		Env:      map[string]string{"GOFLAGS": "-mod=mod", "GOPRIVATE": "example.com"},
+		Settings: map[string]any{"buildFlags": []any{"-tags=a"}, "staticcheck": true},
+	}
+
+	merged := base.Merge(GoplsOptions{
+		Args:     []string{"-logfile=gopls.log"},
+		Env:      map[string]string{"GOFLAGS": "-mod=vendor"},
+		Settings: map[string]any{"buildFlags": []any{"-tags=b"}},
+	})
+
+	if merged.Path != "/opt/gopls" {
+		t.Errorf("expected the base path to be kept, got %q", merged.Path)
+	}
+	if strings.Join(merged.Args, " ") != "-remote=auto -logfile=gopls.log" {
+		t.Errorf("expected args to be appended, got %v", merged.Args)
+	}
+	if merged.Env["GOFLAGS"] != "-mod=vendor" || merged.Env["GOPRIVATE"] != "example.com" {
+		t.Errorf("unexpected env %v", merged.Env)
+	}
+	if merged.Settings["buildFlags"].([]any)[0] != "-tags=b" || merged.Settings["staticcheck"] != true {
+		t.Errorf("unexpected settings %v", merged.Settings)
+	}
+	if base.Env["GOFLAGS"] != "-mod=mod" {
+		t.Errorf("Merge modified the receiver")
+	}
+
+	if merged := base.Merge(GoplsOptions{Path: "gopls-dev"}); merged.Path != "gopls-dev" {
+		t.Errorf("expected the override path, got %q", merged.Path)
+	}
+	if merged := (GoplsOptions{}).Merge(GoplsOptions{}); merged.Env != nil || merged.Settings != nil {
+		t.Errorf("expected empty options, got %+v", merged)
+	}
+}
@@ -0,0 +1,403 @@
+package goalias
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"os"
+
+	"github.com/jackchuka/goalias/internal/diff"
+	"github.com/jackchuka/goalias/internal/discovery/ast"
+	"github.com/jackchuka/goalias/internal/lsp"
+	"github.com/jackchuka/goalias/internal/rename"
+	"go.lsp.dev/protocol"
+)
+
+// ConflictPolicy selects what Plan does when the required alias collides with
+// an identifier in a file
+type ConflictPolicy string
+
+// Conflict policies
+const (
+	// ConflictAbort fails the plan with a *ConflictError
+	ConflictAbort ConflictPolicy = "abort"
+	// ConflictSkip leaves the import alone
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictFallback uses the first free alias of PlanOptions.FallbackAlias
+	ConflictFallback ConflictPolicy = "fallback"
+)
+
+// DefaultFallbackAlias is the fallback alias pattern used when none is given:
+// the required alias followed by a number counting from 2
+const DefaultFallbackAlias = "{alias}{n}"
+
+// PlanOptions configures Plan
+type PlanOptions struct {
+	// Engine renames imports. Nil uses ASTEngine. Files excluded by build
+	// constraints are always renamed with ASTEngine.
+	Engine Engine
+
+	// OnConflict defaults to ConflictAbort
+	OnConflict ConflictPolicy
+	// FallbackAlias is the alias pattern tried with ConflictFallback.
+	// {alias} expands to the required alias and {n} to a number counting
+	// from 2. It defaults to DefaultFallbackAlias.
+	FallbackAlias string
+
+	// ConvertDotImports converts dot imports to named imports, qualifying
+	// every reference. Otherwise they are skipped.
+	ConvertDotImports bool
+
+	// Progress, if set, is called before each import is rewritten with its
+	// 1-based index and the number of imports to rewrite. Duplicate imports
+	// of a path in a file are merged in one step and counted once.
+	Progress func(index, total int, v Violation)
+	// Notify, if set, receives every Notice
+	Notify func(Notice)
+}
+
+func (o PlanOptions) notify(n Notice) {
+	if o.Notify != nil {
+		o.Notify(n)
+	}
+}
+
+// NoticeKind identifies what a Notice reports
+type NoticeKind int
+
+// Notice kinds
+const (
+	// NoticeDotImportSkipped is a dot import left alone because
+	// ConvertDotImports is off
+	NoticeDotImportSkipped NoticeKind = iota + 1
+	// NoticeConflict is an identifier at Position the required alias
+	// collides with; Message describes it
+	NoticeConflict
+	// NoticeConflictSkipped is an import skipped under ConflictSkip
+	NoticeConflictSkipped
+	// NoticeFallback is an import renamed to the fallback Alias instead
+	NoticeFallback
+	// NoticeNoFallback is a conflicting import for which no fallback alias
+	// is free
+	NoticeNoFallback
+	// NoticeMerged is a file importing the path under several names, which
+	// are merged into a single import
+	NoticeMerged
+)
+
+// Notice reports an import Plan skipped or handled specially
+type Notice struct {
+	Kind      NoticeKind
+	Violation Violation
+
+	// Position and Message describe the colliding identifier of a
+	// NoticeConflict
+	Position token.Position
+	Message  string
+	// Alias is the alias chosen for a NoticeFallback
+	Alias string
+}
+
+// ConflictError is returned by Plan under ConflictAbort when required aliases
+// collide with identifiers; each collision is reported as a NoticeConflict
+type ConflictError struct {
+	Files int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("alias conflicts in %d file(s)", e.Files)
+}
+
+// FileChange is the planned new content of one file
+type FileChange struct {
+	Path     string
+	Original []byte
+	Modified []byte
+	Mode     os.FileMode
+}
+
+// Diff returns a unified diff of the change with the given number of context
+// lines
+func (c FileChange) Diff(context int) string {
+	return diff.Unified(c.Path, c.Path, string(c.Original), string(c.Modified), context)
+}
+
+// Plan computes the changes that rename every fixable violation to its
+// required alias, without modifying any file. Every rename is computed
+// against the unmodified tree and validated together, so the result applies
+// cleanly or Plan fails. Violations that are not Fixable are ignored.
+func Plan(ctx context.Context, violations []Violation, opts PlanOptions) ([]FileChange, error) {
+	switch opts.OnConflict {
+	case "":
+		opts.OnConflict = ConflictAbort
+	case ConflictAbort, ConflictSkip, ConflictFallback:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q: must be %s, %s or %s", opts.OnConflict, ConflictAbort, ConflictSkip, ConflictFallback)
+	}
+	if opts.FallbackAlias == "" {
+		opts.FallbackAlias = DefaultFallbackAlias
+	}
+	if opts.Engine == nil {
+		opts.Engine = ASTEngine()
+	}
+
+	var targets []Violation
+	for _, v := range violations {
+		switch {
+		case !v.Fixable():
+		case v.Name == "." && !opts.ConvertDotImports:
+			opts.notify(Notice{Kind: NoticeDotImportSkipped, Violation: v})
+		default:
+			targets = append(targets, v)
+		}
+	}
+
+	// Alias templates in pattern rules can expand to invalid names
+	for _, v := range targets {
+		if !token.IsIdentifier(v.Required) {
+			return nil, fmt.Errorf("%s: required alias %q for %s is not a Go identifier", v.File, v.Required, v.Path)
+		}
+	}
+
+	targets, err := resolveConflicts(ctx, targets, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// A file importing the path under several names is merged once, which
+	// covers every violation of that path in the file
+	var work []Violation
+	merge := make(map[string]bool)
+
+	for _, target := range targets {
+		key := target.File + "\x00" + target.Path
+		duplicated, checked := merge[key]
+		if !checked {
+			if duplicated, err = hasDuplicateImports(target); err != nil {
+				return nil, fmt.Errorf("failed to process %s: %w", target.File, err)
+			}
+			merge[key] = duplicated
+		} else if duplicated {
+			continue
+		}
+		work = append(work, target)
+	}
+
+	changes := lsp.NewChangeSet()
+
+	for i, target := range work {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(work), target)
+		}
+
+		if merge[target.File+"\x00"+target.Path] {
+			opts.notify(Notice{Kind: NoticeMerged, Violation: target})
+			if err := mergeImports(changes, target); err != nil {
+				return nil, fmt.Errorf("failed to process %s: %w", target.File, err)
+			}
+			continue
+		}
+
+		if err := renameImport(ctx, opts.Engine, changes, target); err != nil {
+			return nil, fmt.Errorf("failed to process %s: %w", target.File, err)
+		}
+	}
+
+	prepared, err := changes.Prepare()
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply changes: %w", err)
+	}
+
+	fileChanges := make([]FileChange, 0, len(prepared))
+	for _, c := range prepared {
+		fileChanges = append(fileChanges, FileChange(c))
+	}
+
+	return fileChanges, nil
+}
+
+// Apply writes every change atomically: all files are staged first, and files
+// already replaced are restored if a later one fails
+func Apply(ctx context.Context, changes []FileChange) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	converted := make([]lsp.FileChange, 0, len(changes))
+	for _, c := range changes {
+		converted = append(converted, lsp.FileChange(c))
+	}
+
+	if err := lsp.WriteChanges(converted); err != nil {
+		return fmt.Errorf("failed to apply changes: %w", err)
+	}
+
+	return nil
+}
+
+// resolveConflicts type-checks each target file for identifiers the new alias
+// would collide with and applies the conflict policy to those that have any
+func resolveConflicts(ctx context.Context, targets []Violation, opts PlanOptions) ([]Violation, error) {
+	var kept []Violation
+	unresolved := make(map[string]bool)
+
+	// Duplicate imports of a path in a file are merged into one, so the
+	// file and path are checked once and every spec shares the outcome: the
+	// alias to use, or "" to leave them alone
+	resolved := make(map[string]string)
+
+	for _, target := range targets {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Dropping a redundant name changes no reference
+		if target.Name == target.Required {
+			kept = append(kept, target)
+			continue
+		}
+
+		key := target.File + "\x00" + target.Path
+		if alias, ok := resolved[key]; ok {
+			if alias != "" {
+				target.Required = alias
+				kept = append(kept, target)
+			}
+			continue
+		}
+
+		line := target.Line - 1
+		column := target.Column - 1
+
+		conflicts, err := rename.FindConflicts(target.File, line, column, target.Required)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s for conflicts: %w", target.File, err)
+		}
+
+		if len(conflicts) == 0 {
+			resolved[key] = target.Required
+			kept = append(kept, target)
+			continue
+		}
+
+		for _, c := range conflicts {
+			opts.notify(Notice{Kind: NoticeConflict, Violation: target, Position: c.Position, Message: c.Message})
+		}
+
+		resolved[key] = ""
+
+		switch opts.OnConflict {
+		case ConflictSkip:
+			opts.notify(Notice{Kind: NoticeConflictSkipped, Violation: target})
+			continue
+		case ConflictFallback:
+			if alias, ok := findFallbackAlias(target, line, column, opts.FallbackAlias); ok {
+				resolved[key] = alias
+				if alias == target.Alias {
+					// The file already uses a fallback alias
+					continue
+				}
+				opts.notify(Notice{Kind: NoticeFallback, Violation: target, Alias: alias})
+				target.Required = alias
+				kept = append(kept, target)
+				continue
+			}
+			opts.notify(Notice{Kind: NoticeNoFallback, Violation: target})
+		}
+
+		unresolved[target.File] = true
+	}
+
+	if len(unresolved) > 0 {
+		return nil, &ConflictError{Files: len(unresolved)}
+	}
+
+	return kept, nil
+}
+
+// findFallbackAlias returns the first candidate of pattern that does not
+// conflict in the target file
+func findFallbackAlias(target Violation, line, column int, pattern string) (string, bool) {
+	for _, alias := range rename.FallbackAliases(pattern, target.Required) {
+		conflicts, err := rename.FindConflicts(target.File, line, column, alias)
+		if err == nil && len(conflicts) == 0 {
+			return alias, true
+		}
+	}
+	return "", false
+}
+
+// hasDuplicateImports reports whether the target file imports its path under
+// more than one name. Blank and dot imports are not counted.
+func hasDuplicateImports(target Violation) (bool, error) {
+	specs, err := ast.FindImportSpecsInFile(target.File, []string{target.Path})
+	if err != nil {
+		return false, fmt.Errorf("failed to parse file: %w", err)
+	}
+
+	named := 0
+	for _, spec := range specs {
+		if spec.Alias != "_" && spec.Alias != "." {
+			named++
+		}
+	}
+	return named > 1, nil
+}
+
+// mergeImports collapses every import of the target path in its file into one
+// named target.Required and adds the edit to changes. Merging needs no type
+// information beyond the file, so the AST engine is used whatever the engine.
+func mergeImports(changes *lsp.ChangeSet, target Violation) error {
+	workspaceEdit, err := rename.NewASTEngine().Merge(target.File, target.Path, target.Required)
+	if err != nil {
+		return fmt.Errorf("merge failed: %w", err)
+	}
+
+	return addEdit(changes, workspaceEdit)
+}
+
+// renameImport computes the rename for target and adds it to changes
+func renameImport(ctx context.Context, engine Engine, changes *lsp.ChangeSet, target Violation) error {
+	// Convert the 1-based token position to an LSP position
+	line := target.Line - 1
+	column := target.Column - 1
+
+	var (
+		workspaceEdit *protocol.WorkspaceEdit
+		err           error
+	)
+	switch target.Name {
+	case ".":
+		// Dot imports have no name to rename; every reference is
+		// qualified instead
+		workspaceEdit, err = rename.ConvertDotImport(target.File, line, column, target.Required)
+	case target.Required:
+		// Dropping a redundant name leaves every reference as it is, so
+		// the built-in engine handles it without starting anything
+		workspaceEdit, err = rename.NewASTEngine().Rename(target.File, line, column, target.Required)
+	default:
+		if target.BuildIgnored {
+			// Engines such as gopls only load files for the current build
+			// configuration, while the built-in one checks each file alone
+			workspaceEdit, err = rename.NewASTEngine().Rename(target.File, line, column, target.Required)
+			break
+		}
+		workspaceEdit, err = engine.Rename(ctx, target.File, line, column, target.Required)
+	}
+	if err != nil {
+		return fmt.Errorf("rename operation failed: %w", err)
+	}
+
+	return addEdit(changes, workspaceEdit)
+}
+
+func addEdit(changes *lsp.ChangeSet, workspaceEdit *protocol.WorkspaceEdit) error {
+	if err := changes.Add(workspaceEdit); err != nil {
+		return fmt.Errorf("failed to collect workspace edit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package goalias
+
+import (
+	"context"
+	"io"
+
+	"github.com/jackchuka/goalias/internal/config"
+	"github.com/jackchuka/goalias/internal/policy"
+)
+
+// PolicyFileName is the name of the policy file DiscoverPolicy looks for
+const PolicyFileName = config.FileName
+
+// Rule IDs reported with violations
+const (
+	// RuleRequiredAlias is an import whose alias differs from the one a rule
+	// or target requires
+	RuleRequiredAlias = policy.RuleRequiredAlias
+	// RuleUnaliased is an aliased import of a path that must not be aliased
+	RuleUnaliased = policy.RuleUnaliased
+	// RuleForbiddenAlias is an import using a forbidden alias
+	RuleForbiddenAlias = policy.RuleForbiddenAlias
+)
+
+// Policy is a parsed alias policy file
+type Policy struct {
+	cfg *config.Config
+}
+
+// LoadPolicy reads and validates the policy file at path
+func LoadPolicy(path string) (*Policy, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Policy{cfg: cfg}, nil
+}
+
+// DiscoverPolicy finds and loads the policy file for dir by walking up to the
+// directory containing go.mod. It returns nil without an error when there is
+// none.
+func DiscoverPolicy(dir string) (*Policy, error) {
+	cfg, err := config.Discover(dir)
+	if err != nil || cfg == nil {
+		return nil, err
+	}
+	return &Policy{cfg: cfg}, nil
+}
+
+// ParsePolicy decodes and validates policy file data. Override directories
+// are resolved against root.
+func ParsePolicy(data []byte, root string) (*Policy, error) {
+	cfg, err := config.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Root = root
+	return &Policy{cfg: cfg}, nil
+}
+
+// Path returns the file the policy was loaded from, or an empty string for a
+// parsed policy
+func (p *Policy) Path() string {
+	if p == nil {
+		return ""
+	}
+	return p.cfg.Path
+}
+
+// Empty reports whether the policy has no rules
+func (p *Policy) Empty() bool {
+	return p.config().Empty()
+}
+
+// AliasFor returns the alias the policy requires for importPath in file
+func (p *Policy) AliasFor(file, importPath string) (string, bool) {
+	return p.config().AliasFor(file, importPath)
+}
+
+// Gopls returns the gopls settings of the policy file
+func (p *Policy) Gopls() GoplsOptions {
+	if p == nil {
+		return GoplsOptions{}
+	}
+	gopls := p.cfg.Gopls
+	return GoplsOptions{Path: gopls.Path, Args: gopls.Args, Env: gopls.Env, Settings: gopls.Settings}
+}
+
+func (p *Policy) config() *config.Config {
+	if p == nil {
+		return nil
+	}
+	return p.cfg
+}
+
+// Target is an import path to enforce. An empty Alias defers to the policy.
+type Target struct {
+	Path  string
+	Alias string
+}
+
+// ParseTarget parses "path" or "path=alias"
+func ParseTarget(s string) (Target, error) {
+	t, err := policy.ParseTarget(s)
+	if err != nil {
+		return Target{}, err
+	}
+	return Target(t), nil
+}
+
+// ParseMapping reads targets from a mapping file with one "path=alias" pair
+// per line. Blank lines and lines starting with # are ignored.
+func ParseMapping(r io.Reader) ([]Target, error) {
+	targets, err := policy.ParseMapping(r)
+	if err != nil {
+		return nil, err
+	}
+	return convertTargets(targets), nil
+}
+
+func convertTargets(targets []policy.Target) []Target {
+	converted := make([]Target, 0, len(targets))
+	for _, t := range targets {
+		converted = append(converted, Target(t))
+	}
+	return converted
+}
+
+func internalTargets(targets []Target) []policy.Target {
+	converted := make([]policy.Target, 0, len(targets))
+	for _, t := range targets {
+		converted = append(converted, policy.Target(t))
+	}
+	return converted
+}
+
+// Requirement is an import governed by a target or the policy, with the alias
+// it is required to use
+type Requirement struct {
+	Import
+	Required string
+}
+
+// Violation is an import breaking a rule of the policy
+type Violation struct {
+	Import
+	// Rule is the ID of the broken rule, such as RuleRequiredAlias
+	Rule string
+	// Required is the alias that fixes the violation, or empty when it cannot
+	// be fixed automatically
+	Required string
+	// Reason is the configured explanation of a forbidden alias
+	Reason string
+}
+
+// Fixable reports whether renaming the import to Required fixes the violation
+func (v Violation) Fixable() bool {
+	return v.Required != ""
+}
+
+// Message describes the violation
+func (v Violation) Message() string {
+	return v.internal().Message()
+}
+
+func (v Violation) internal() policy.Violation {
+	return policy.Violation{ImportResult: v.result(), Rule: v.Rule, Required: v.Required, Reason: v.Reason}
+}
+
+func newViolation(v policy.Violation) Violation {
+	return Violation{Import: newImport(v.ImportResult), Rule: v.RuleID(), Required: v.Required, Reason: v.Reason}
+}
+
+// Check returns every import in the files selected by opts that breaks the
+// policy. When targets are given, only their paths are checked and a
+// target's alias takes precedence over the policy. With no targets, the
+// unaliased and forbidden rules of the policy are enforced too. p may be nil
+// when every target has an alias.
+func Check(ctx context.Context, opts Options, p *Policy, targets ...Target) ([]Violation, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	found, err := policy.Check(opts.patterns(), opts.discovery(), internalTargets(targets), p.config())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	violations := make([]Violation, 0, len(found))
+	for _, v := range found {
+		violations = append(violations, newViolation(v))
+	}
+
+	return violations, nil
+}
+
+// Evaluate returns every import in the files selected by opts that a target
+// or the policy requires an alias for, whether or not it already uses it.
+// Blank imports are skipped.
+func Evaluate(ctx context.Context, opts Options, p *Policy, targets ...Target) ([]Requirement, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results, err := policy.Evaluate(opts.patterns(), opts.discovery(), internalTargets(targets), p.config())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	requirements := make([]Requirement, 0, len(results))
+	for _, r := range results {
+		requirements = append(requirements, Requirement{Import: newImport(r.ImportResult), Required: r.Required})
+	}
+
+	return requirements, nil
+}
+
+// WriteText writes violations in a compiler-like "file:line:col: message (rule)"
+// format. relPath rewrites file names for display and may be nil.
+func WriteText(w io.Writer, violations []Violation, relPath func(string) string) error {
+	return policy.WriteText(w, internalViolations(violations), relPath)
+}
+
+// WriteGitHub writes violations as GitHub Actions error annotations
+func WriteGitHub(w io.Writer, violations []Violation, relPath func(string) string) error {
+	return policy.WriteGitHub(w, internalViolations(violations), relPath)
+}
+
+func internalViolations(violations []Violation) []policy.Violation {
+	converted := make([]policy.Violation, 0, len(violations))
+	for _, v := range violations {
+		converted = append(converted, v.internal())
+	}
+	return converted
+}